@@ -0,0 +1,269 @@
+package cmtjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Pos is a position in the source, Line and Column start from 1,
+// Column counts bytes
+type Pos struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// CommentKind is the syntax of a comment
+type CommentKind int
+
+// comment syntaxes
+const (
+	SharpComment CommentKind = iota // # comment
+	SlashComment                    // // comment
+	BlockComment                    // /* comment */
+)
+
+// Comment is a comment in the source
+type Comment struct {
+	Kind CommentKind
+	Text string // the raw text, include the comment markers
+	Pos  Pos
+}
+
+// NodeKind is the kind of a json value
+type NodeKind int
+
+// json value kinds
+const (
+	ObjectNode NodeKind = iota
+	ArrayNode
+	StringNode
+	NumberNode
+	BoolNode
+	NullNode
+)
+
+func (k NodeKind) String() string {
+	switch k {
+	case ObjectNode:
+		return "object"
+	case ArrayNode:
+		return "array"
+	case StringNode:
+		return "string"
+	case NumberNode:
+		return "number"
+	case BoolNode:
+		return "bool"
+	case NullNode:
+		return "null"
+	}
+	return "unknown"
+}
+
+// Node is a json value in the document
+type Node struct {
+	Kind NodeKind
+	Pos  Pos // position of the first byte of the value
+	End  Pos // position after the last byte of the value
+
+	Raw     string    // raw text of a scalar value
+	Members []*Member // members of an object
+	Elems   []*Node   // elements of an array
+
+	Comments         []Comment // comments before the value
+	TrailingComments []Comment // comments after the value on the same line
+	EndComments      []Comment // comments before the closing "}" or "]"
+
+	// the source layout, used to print the document byte-for-byte
+	before      string // whitespace and comments before the value
+	commaBefore string // whitespace and comments before the following ","
+	hasComma    bool   // an array element followed by ","
+	endBefore   string // whitespace and comments before "}" or "]"
+}
+
+// Member is a key-value pair of an object
+type Member struct {
+	Key    string // the decoded key
+	KeyRaw string // the raw key, include the quotes
+	KeyPos Pos
+	Value  *Node
+
+	Comments []Comment // comments before the key
+
+	keyBefore   string // whitespace and comments before the key
+	colonBefore string // whitespace and comments before ":"
+	commaBefore string // whitespace and comments before the following ","
+	hasComma    bool
+}
+
+// Document is a parsed commented json document
+type Document struct {
+	Root     *Node
+	Comments []Comment // comments after the root value
+
+	after string // whitespace and comments after the root value
+}
+
+// Get return the member value of an object node by key, nil if not exists
+func (n *Node) Get(key string) *Node {
+	if n == nil || n.Kind != ObjectNode {
+		return nil
+	}
+	for _, m := range n.Members {
+		if m.Key == key {
+			return m.Value
+		}
+	}
+	return nil
+}
+
+// Index return the i-th element of an array node, nil if not exists
+func (n *Node) Index(i int) *Node {
+	if n == nil || n.Kind != ArrayNode || i < 0 || i >= len(n.Elems) {
+		return nil
+	}
+	return n.Elems[i]
+}
+
+// Find return the node of a dotted path like "servers[2].host", nil if not exists
+func (d *Document) Find(path string) *Node {
+	n := d.Root
+	if path == "" {
+		return n
+	}
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key, part = part[:i], part[i:]
+		} else {
+			part = ""
+		}
+		if key != "" {
+			n = n.Get(key)
+		}
+		for part != "" {
+			end := strings.IndexByte(part, ']')
+			if part[0] != '[' || end < 0 {
+				return nil
+			}
+			idx, err := strconv.Atoi(part[1:end])
+			if err != nil {
+				return nil
+			}
+			n = n.Index(idx)
+			part = part[end+1:]
+		}
+		if n == nil {
+			return nil
+		}
+	}
+	return n
+}
+
+// Interface decode the node into the types json.Unmarshal produce
+func (n *Node) Interface() (interface{}, error) {
+	switch n.Kind {
+	case ObjectNode:
+		m := make(map[string]interface{}, len(n.Members))
+		for _, mb := range n.Members {
+			v, err := mb.Value.Interface()
+			if err != nil {
+				return nil, err
+			}
+			m[mb.Key] = v
+		}
+		return m, nil
+	case ArrayNode:
+		s := make([]interface{}, len(n.Elems))
+		for i, e := range n.Elems {
+			v, err := e.Interface()
+			if err != nil {
+				return nil, err
+			}
+			s[i] = v
+		}
+		return s, nil
+	}
+	var v interface{}
+	if err := json.Unmarshal([]byte(n.Raw), &v); err != nil {
+		return nil, fmt.Errorf("cmtjson: %s: %s", n.Pos, err)
+	}
+	return v, nil
+}
+
+// SetValue replace the node value with v encoded as json,
+// comments around the node are kept, comments inside the old value are dropped
+func (n *Node) SetValue(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	doc, err := ParseAST(data)
+	if err != nil {
+		return err
+	}
+	nn := doc.Root
+	n.Kind, n.Raw, n.Members, n.Elems = nn.Kind, nn.Raw, nn.Members, nn.Elems
+	n.EndComments, n.endBefore = nil, ""
+	return nil
+}
+
+// Bytes print the document, an unchanged document is printed byte-for-byte
+func (d *Document) Bytes() []byte {
+	buf := &bytes.Buffer{}
+	d.WriteTo(buf)
+	return buf.Bytes()
+}
+
+// WriteTo print the document into w
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	buf := &bytes.Buffer{}
+	if d.Root != nil {
+		d.Root.print(buf)
+	}
+	buf.WriteString(d.after)
+	return buf.WriteTo(w)
+}
+
+func (n *Node) print(buf *bytes.Buffer) {
+	buf.WriteString(n.before)
+	switch n.Kind {
+	case ObjectNode:
+		buf.WriteByte('{')
+		for _, m := range n.Members {
+			buf.WriteString(m.keyBefore)
+			buf.WriteString(m.KeyRaw)
+			buf.WriteString(m.colonBefore)
+			buf.WriteByte(':')
+			m.Value.print(buf)
+			buf.WriteString(m.commaBefore)
+			if m.hasComma {
+				buf.WriteByte(',')
+			}
+		}
+		buf.WriteString(n.endBefore)
+		buf.WriteByte('}')
+	case ArrayNode:
+		buf.WriteByte('[')
+		for _, e := range n.Elems {
+			e.print(buf)
+			buf.WriteString(e.commaBefore)
+			if e.hasComma {
+				buf.WriteByte(',')
+			}
+		}
+		buf.WriteString(n.endBefore)
+		buf.WriteByte(']')
+	default:
+		buf.WriteString(n.Raw)
+	}
+}
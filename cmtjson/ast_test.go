@@ -0,0 +1,139 @@
+package cmtjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseASTRoundTrip(t *testing.T) {
+	for caseName, cs := range testCases {
+		doc, err := ParseAST([]byte(cs))
+		if err != nil {
+			t.Fatalf("case: %s => parse error: %s\n", caseName, err)
+		}
+		if out := string(doc.Bytes()); out != cs {
+			t.Fatalf("case: %s => not printed byte-for-byte:\n%s\n---\n%s", caseName, out, cs)
+		}
+
+		v, err := doc.Root.Interface()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var want interface{}
+		if err = ParseFromBytes([]byte(cs), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Fatalf("case: %s => Interface():\n%#v\nwant:\n%#v", caseName, v, want)
+		}
+	}
+}
+
+func TestParseASTComments(t *testing.T) {
+	data := `# head
+{
+	// the name
+	"name": "iyidan", # name trailing
+	/* the list */
+	"list": [
+		1, // one
+		/* two */ 2
+		// end of list
+	],
+	"empty": {} /* empty trailing */
+	// end of object
+}
+// tail`
+	doc, err := ParseAST([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts := func(cmts []Comment) []string {
+		var r []string
+		for _, c := range cmts {
+			r = append(r, c.Text)
+		}
+		return r
+	}
+	check := func(name string, cmts []Comment, want ...string) {
+		if got := texts(cmts); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s comments: %q, want %q", name, got, want)
+		}
+	}
+
+	root := doc.Root
+	check("root", root.Comments, "# head")
+	check("root end", root.EndComments, "// end of object")
+	check("doc", doc.Comments, "// tail")
+	check("name", root.Members[0].Comments, "// the name")
+	check("name trailing", root.Get("name").TrailingComments, "# name trailing")
+	check("list", root.Members[1].Comments, "/* the list */")
+	check("list[0] trailing", doc.Find("list[0]").TrailingComments, "// one")
+	check("list[1]", doc.Find("list[1]").Comments, "/* two */")
+	check("list end", root.Get("list").EndComments, "// end of list")
+	check("empty trailing", root.Get("empty").TrailingComments, "/* empty trailing */")
+
+	if root.Members[0].Comments[0].Kind != SlashComment || root.Members[1].Comments[0].Kind != BlockComment || root.Comments[0].Kind != SharpComment {
+		t.Fatal("comment kind error")
+	}
+	name := root.Get("name")
+	if name.Pos.Line != 4 || name.Pos.Column != 10 || name.Raw != `"iyidan"` {
+		t.Fatalf("name node: %#v", name)
+	}
+	if kp := root.Members[0].KeyPos; kp.Line != 4 || kp.Column != 2 || kp.Offset != strings.Index(data, `"name"`) {
+		t.Fatalf("name key pos: %#v", kp)
+	}
+}
+
+func TestNodeSetValue(t *testing.T) {
+	data := `{
+	// the name
+	"name": "iyidan", # name trailing
+	"list": [1, 2]
+}`
+	doc, err := ParseAST([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = doc.Find("name").SetValue("goutils"); err != nil {
+		t.Fatal(err)
+	}
+	if err = doc.Find("list").SetValue(map[string]int{"a": 1}); err != nil {
+		t.Fatal(err)
+	}
+	want := `{
+	// the name
+	"name": "goutils", # name trailing
+	"list": {"a":1}
+}`
+	if out := string(doc.Bytes()); out != want {
+		t.Fatalf("SetValue result:\n%s\nwant:\n%s", out, want)
+	}
+	if doc.Find("list.a").Raw != "1" || doc.Find("list[0]") != nil || doc.Find("nope") != nil {
+		t.Fatal("Find error")
+	}
+}
+
+func TestParseASTErrors(t *testing.T) {
+	cases := map[string]string{
+		``:                "unexpected end of input",
+		`{"a":1,}`:        "after ','",
+		`[1,]`:            "after ','",
+		`{"a" 1}`:         "want ':'",
+		`{"a":1 "b":2}`:   "want ',' or '}'",
+		`{a:1}`:           "want a string key",
+		"{\"a\":\n  01}":  "line 2 column 3: invalid number",
+		`{"a":"b}`:        "unterminated string",
+		`{"a":tru}`:       "invalid literal",
+		`{"a":1} /* open`: "unterminated block comment",
+		`{"a":1} x`:       "after the root value",
+		`{"a":"\x"}`:      "invalid string",
+	}
+	for data, msg := range cases {
+		_, err := ParseAST([]byte(data))
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("%q: want error contains %q, got: %v", data, msg, err)
+		}
+	}
+}
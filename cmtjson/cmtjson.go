@@ -2,7 +2,6 @@ package cmtjson
 
 import (
 	"bufio"
-	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -19,199 +18,155 @@ var (
 	btBackslash byte = '\\'
 	btLineBreak byte = '\n'
 
-	// WriteBufSize the buffer size for write, default 1MB
+	// WriteBufSize files not larger than it are read at once, default 1MB,
+	// the larger ones are decoded while reading
 	WriteBufSize = 1 << 20
 )
 
-func newStore(probableSize int) (storer, error) {
-	if probableSize > WriteBufSize {
-		return newFileStore()
-	}
-	return &bytesStore{&bytes.Buffer{}}, nil
-}
-
 // RemoveJSONCommentBytes remove comment from json data
-// remove "#", "//", "/* ... */" comments
+// remove "#", "//", "/* ... */" comments, the comments are replaced
+// with spaces in place, so the offsets and lines of data not change
 func RemoveJSONCommentBytes(data []byte) []byte {
-	var (
-		prev byte
+	data, _ = removeComments(data, Options{})
+	return data
+}
 
-		inSharpCmt, inSlashCmt, inBlockCmt, inJSONStr bool
-	)
+// removeComments remove the comments allowed by o from data in place,
+// a disallowed comment is returned as *SyntaxError
+func removeComments(data []byte, o Options) ([]byte, error) {
+	s := newStripper(o)
 	for i := 0; i < len(data); i++ {
-		wt := false
-		reset := false
-		switch {
-		case inSharpCmt, inSlashCmt:
-			if data[i] == btLineBreak {
-				inSharpCmt = false
-				inSlashCmt = false
-			}
-		case inBlockCmt:
-			if prev == btStar && data[i] == btSlash {
-				inBlockCmt = false
-				reset = true
-			}
-		case inJSONStr:
-			if data[i] == btDbQuote && prev != btBackslash {
-				inJSONStr = false
-			}
-			wt = true
-		default:
-			if data[i] == btSharp {
-				inSharpCmt = true
-			} else if prev == btSlash && data[i] == btSlash {
-				inSlashCmt = true
-			} else if prev == btSlash && data[i] == btStar {
-				inBlockCmt = true
-			} else {
-				wt = true
-				if data[i] == btDbQuote && prev != btBackslash {
-					inJSONStr = true
-				}
-			}
+		if c, ok := s.step(data[i]); ok {
+			data[i-1] = c
 		}
-		// write byte
-		if i > 0 && (!wt || prev == 0) {
-			data[i-1] = btSpace
-		}
-		// block comment need reset last byte to zero
-		if reset {
-			prev = 0
-		} else {
-			prev = data[i]
+		if s.err != nil {
+			s.err.setSource(data)
+			return nil, s.err
 		}
 	}
-
-	// write last byte
-	if inSharpCmt || inSlashCmt || inBlockCmt || prev == 0 {
-		data[len(data)-1] = btSpace
+	if c, ok := s.flush(); ok {
+		data[len(data)-1] = c
 	}
-	return data
-}
-
-// RemoveJSONComment remove comment from r which contains json data
-// remove "#", "//", "/* ... */" comments
-func RemoveJSONComment(r io.Reader, probableSize int) (io.ReadCloser, error) {
-	store, err := newStore(probableSize)
-	if err != nil {
-		return nil, err
+	if s.err != nil {
+		s.err.setSource(data)
+		return nil, s.err
 	}
+	return data, nil
+}
 
-	var (
-		writer = bufio.NewWriterSize(store, WriteBufSize)
-		frag   = make([]byte, 4096) // 4kb read size
+// reader remove the comments of r on the fly
+type reader struct {
+	r       io.Reader
+	s       *stripper
+	err     error
+	flushed bool
 
-		n    int
-		prev byte
+	offset int   // bytes read from r
+	lines  []int // offsets of the line starts, to locate the syntax errors
+}
 
-		inSharpCmt, inSlashCmt, inBlockCmt, inJSONStr bool
-	)
+// NewReader return a reader which remove the comments of r on the fly,
+// the comments are replaced with spaces like RemoveJSONCommentBytes
+func NewReader(r io.Reader) io.Reader {
+	return newReader(r, Options{})
+}
 
-	for {
-		n, err = r.Read(frag)
-		if n <= 0 {
-			break
-		}
+func newReader(r io.Reader, o Options) *reader {
+	return &reader{r: r, s: newStripper(o), lines: []int{0}}
+}
 
-		for i := 0; i < n; i++ {
-			wt := false
-			reset := false
-			switch {
-			case inSharpCmt, inSlashCmt:
-				if frag[i] == btLineBreak {
-					inSharpCmt = false
-					inSlashCmt = false
-				}
-			case inBlockCmt:
-				if prev == btStar && frag[i] == btSlash {
-					inBlockCmt = false
-					reset = true
-				}
-			case inJSONStr:
-				if frag[i] == btDbQuote && prev != btBackslash {
-					inJSONStr = false
-				}
-				wt = true
-			default:
-				if frag[i] == btSharp {
-					inSharpCmt = true
-				} else if prev == btSlash && frag[i] == btSlash {
-					inSlashCmt = true
-				} else if prev == btSlash && frag[i] == btStar {
-					inBlockCmt = true
-				} else {
-					wt = true
-					if frag[i] == btDbQuote && prev != btBackslash {
-						inJSONStr = true
-					}
+func (r *reader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	n := 0
+	for n == 0 {
+		if r.err != nil {
+			if !r.flushed {
+				r.flushed = true
+				c, ok := r.s.flush()
+				if r.s.err != nil {
+					r.err = r.locate(r.s.err)
+				} else if ok {
+					p[0] = c
+					return 1, nil
 				}
 			}
-			// write byte
-			if wt && prev > 0 {
-				err = writer.WriteByte(prev)
-				if err != nil {
-					break
-				}
+			return 0, r.err
+		}
+		// strip in place, the output of p[i] is written to p[n] and n <= i
+		rn, err := r.r.Read(p)
+		for i := 0; i < rn; i++ {
+			if p[i] == btLineBreak {
+				r.lines = append(r.lines, r.offset+i+1)
 			}
-			// block comment need reset last byte to zero
-			if reset {
-				prev = 0
-			} else {
-				prev = frag[i]
+			if c, ok := r.s.step(p[i]); ok {
+				p[n] = c
+				n++
+			}
+			if r.s.err != nil {
+				// the line starts after the error are not needed
+				r.offset += i + 1
+				r.err, r.flushed = r.locate(r.s.err), true
+				return n, nil
 			}
 		}
-
-		// read error or EOF
-		if err != nil {
-			break
-		}
+		r.offset += rn
+		r.err = err
 	}
+	return n, nil
+}
 
-	if err != nil && err != io.EOF {
-		writer.Flush()
-		store.Close()
-		return nil, err
-	}
+// RemoveJSONComment remove comment from r which contains json data
+// remove "#", "//", "/* ... */" comments, the comments are replaced
+// with spaces like RemoveJSONCommentBytes, so the offsets and lines not change.
+// the comments are removed on the fly, probableSize is not used any more
+func RemoveJSONComment(r io.Reader, probableSize int) (io.ReadCloser, error) {
+	return ioutil.NopCloser(NewReader(r)), nil
+}
 
-	// write last byte
-	if !inSharpCmt && !inSlashCmt && !inBlockCmt && prev > 0 {
-		err = writer.WriteByte(prev)
+// ParseFromReader parse the json data with comment from reader r,
+// the data is decoded while reading, probableSize is not used any more.
+// a syntax error is returned as *SyntaxError.
+// the dialect can be selected by opts, see Options, the JSON5 data is read at once
+func ParseFromReader(r io.Reader, v interface{}, probableSize int, opts ...Options) error {
+	o := option(opts)
+	if o.JSON5 {
+		data, err := ioutil.ReadAll(r)
 		if err != nil {
-			writer.Flush()
-			store.Close()
-			return nil, err
+			return err
 		}
+		return parseJSON5(data, v, o)
 	}
-
-	err = writer.Flush()
-	if err != nil {
-		store.Close()
+	dec := newDecoder(r, o)
+	err := dec.Decode(v)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return dec.r.syntaxError(dec.r.offset, "unexpected end of JSON input")
 	}
-	store.Ready()
-	return store, nil
-}
-
-// ParseFromReader parse the json data with comment from reader r
-func ParseFromReader(r io.Reader, v interface{}, probableSize int) error {
-	rc, err := RemoveJSONComment(r, probableSize)
 	if err != nil {
 		return err
 	}
-	defer rc.Close()
-	d, err := ioutil.ReadAll(rc)
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(d, v)
-	if err != nil {
-		return err
+	// only one value is allowed like json.Unmarshal
+	offset := int(dec.InputOffset())
+	rest := bufio.NewReader(io.MultiReader(dec.dec.Buffered(), dec.r))
+	for {
+		c, err := rest.ReadByte()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if c != btSpace && c != '\t' && c != '\r' && c != btLineBreak {
+			return dec.r.syntaxError(offset, fmt.Sprintf("invalid character %q after top-level value", c))
+		}
+		offset++
 	}
-	return nil
 }
 
 // ParseFromFile parse the json data with comment from file
-func ParseFromFile(filename string, v interface{}) error {
+// the dialect can be selected by opts, see Options
+func ParseFromFile(filename string, v interface{}, opts ...Options) error {
 	f, err := os.Open(filename)
 	if err != nil {
 		return err
@@ -228,17 +183,25 @@ func ParseFromFile(filename string, v interface{}) error {
 		if err != nil {
 			return err
 		}
-		return ParseFromBytes(buf, v)
+		return fileSyntaxError(ParseFromBytes(buf, v, opts...), filename)
 	}
 
-	// always file store
-	return ParseFromReader(f, v, int(fInfo.Size()))
+	return fileSyntaxError(ParseFromReader(f, v, int(fInfo.Size()), opts...), filename)
 }
 
 // ParseFromBytes parse the json data with comment from given data
-// use RemoveJSONCommentBytes to improve performance
-func ParseFromBytes(data []byte, v interface{}) error {
-	return json.Unmarshal(RemoveJSONCommentBytes(data), v)
+// use RemoveJSONCommentBytes to improve performance, data is modified in place.
+// the dialect can be selected by opts, see Options
+func ParseFromBytes(data []byte, v interface{}, opts ...Options) error {
+	o := option(opts)
+	if o.JSON5 {
+		return parseJSON5(data, v, o)
+	}
+	data, err := removeComments(data, o)
+	if err != nil {
+		return err
+	}
+	return syntaxError(json.Unmarshal(data, v), data)
 	//return ParseFromReader(bytes.NewReader(data), v, len(data))
 }
 
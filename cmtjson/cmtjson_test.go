@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"encoding/json"
 	"io/ioutil"
+	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -129,6 +131,15 @@ func TestParseFromBytes(t *testing.T) {
 	}
 }
 
+func TestRemoveJSONCommentBytesEmpty(t *testing.T) {
+	if out := RemoveJSONCommentBytes([]byte{}); len(out) != 0 {
+		t.Fatalf("RemoveJSONCommentBytes(empty) = %q", out)
+	}
+	if err := ParseFromBytes(nil, &testData{}); err == nil {
+		t.Fatal("ParseFromBytes(nil) should fail")
+	}
+}
+
 func BenchmarkParseFromBytes(b *testing.B) {
 	b.StopTimer()
 	cases := make(map[string][]byte)
@@ -298,3 +309,48 @@ func checkParseResult(parsed *testData) (bool, *testData) {
 	json.Unmarshal([]byte(testCases["raw"]), &correct)
 	return reflect.DeepEqual(parsed, &correct), &correct
 }
+
+func TestSyntaxError(t *testing.T) {
+	// the block comment has line breaks, the error is on line 5 column 9
+	data := "{\n\t/* a\n\t * b */\n\t\"a\": 1, # c\n\t\"b\": 2 x\n}"
+
+	err := ParseFromBytes([]byte(data), &map[string]interface{}{})
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("want *SyntaxError, got %T: %v", err, err)
+	}
+	if serr.Line != 5 || serr.Column != 9 || serr.Offset != strings.Index(data, "x") {
+		t.Fatalf("wrong position: %d:%d offset %d", serr.Line, serr.Column, serr.Offset)
+	}
+	if serr.Excerpt != "\t\"b\": 2 x\n\t       ^" {
+		t.Fatalf("wrong excerpt:\n%s", serr.Excerpt)
+	}
+	t.Log(serr, "\n"+serr.Excerpt)
+
+	// large file with comments, parsed by the streaming path
+	padding := strings.Repeat("/* padding\n */ # padding\n", WriteBufSize/20)
+	large := strings.Replace(data, "{\n", "{\n"+padding, 1)
+	filename, err := getTempfileWithJSON([]byte(large))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(filename)
+	err = ParseFromFile(filename, &map[string]interface{}{})
+	serr, ok = err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("want *SyntaxError, got %T: %v", err, err)
+	}
+	wantLine := 5 + strings.Count(padding, "\n")
+	if serr.Filename != filename || serr.Line != wantLine || serr.Column != 9 || serr.Offset != strings.Index(large, "x") {
+		t.Fatalf("wrong position: %s", serr)
+	}
+	if serr.Excerpt != "\t\"b\": 2 x\n\t       ^" {
+		t.Fatalf("wrong excerpt:\n%s", serr.Excerpt)
+	}
+
+	// unexpected end
+	err = ParseFromBytes([]byte("{\"a\": [1,\n2"), &map[string]interface{}{})
+	if serr, ok = err.(*SyntaxError); !ok || serr.Line != 2 || serr.Column != 2 {
+		t.Fatalf("wrong error: %v", err)
+	}
+}
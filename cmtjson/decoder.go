@@ -0,0 +1,57 @@
+package cmtjson
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// Decoder read and decode commented json values from an input stream,
+// it mirrors json.Decoder, the comments are removed while reading.
+// syntax errors are returned as *SyntaxError without the excerpt
+type Decoder struct {
+	r   *reader
+	dec *json.Decoder
+}
+
+// NewDecoder return a new decoder that reads from r
+func NewDecoder(r io.Reader) *Decoder {
+	return newDecoder(r, Options{})
+}
+
+func newDecoder(r io.Reader, o Options) *Decoder {
+	cr := newReader(r, o)
+	return &Decoder{r: cr, dec: json.NewDecoder(cr)}
+}
+
+// UseNumber decode a number into an interface{} as a json.Number instead of as a float64
+func (d *Decoder) UseNumber() {
+	d.dec.UseNumber()
+}
+
+// DisallowUnknownFields return an error when the destination is a struct
+// and the input contains object keys which do not match any field
+func (d *Decoder) DisallowUnknownFields() {
+	d.dec.DisallowUnknownFields()
+}
+
+// Decode read the next json value from its input and store it in v
+func (d *Decoder) Decode(v interface{}) error {
+	return d.r.convertError(d.dec.Decode(v))
+}
+
+// Token return the next json token in the input stream, see json.Decoder.Token
+func (d *Decoder) Token() (json.Token, error) {
+	t, err := d.dec.Token()
+	return t, d.r.convertError(err)
+}
+
+// More report whether there is another element in the current array or object being parsed
+func (d *Decoder) More() bool {
+	return d.dec.More()
+}
+
+// InputOffset return the offset of the current decoder position in the source,
+// comments are counted as the source offsets are kept
+func (d *Decoder) InputOffset() int64 {
+	return d.dec.InputOffset()
+}
@@ -0,0 +1,158 @@
+package cmtjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestNewReader(t *testing.T) {
+	for caseName, cs := range testCases {
+		want := RemoveJSONCommentBytes([]byte(cs))
+		got, err := ioutil.ReadAll(NewReader(iotest.OneByteReader(strings.NewReader(cs))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("case: %s => NewReader output differ:\n%s\nwant:\n%s", caseName, got, want)
+		}
+		got, err = ioutil.ReadAll(NewReader(iotest.DataErrReader(strings.NewReader(cs))))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Fatalf("case: %s => NewReader output differ:\n%s\nwant:\n%s", caseName, got, want)
+		}
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	data := `# stream of values
+	{"a": 1 /* one */} // first
+	{"a": 2.5}
+	[1, # list
+	 2, 3]`
+	dec := NewDecoder(strings.NewReader(data))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil || m["a"] != json.Number("1") {
+		t.Fatalf("first value: %v, %v", m, err)
+	}
+	if err := dec.Decode(&m); err != nil || m["a"] != json.Number("2.5") {
+		t.Fatalf("second value: %v, %v", m, err)
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		t.Fatalf("Token: %v, %v", tok, err)
+	}
+	var sum int
+	for dec.More() {
+		var n int
+		if err := dec.Decode(&n); err != nil {
+			t.Fatal(err)
+		}
+		sum += n
+	}
+	if sum != 6 {
+		t.Fatalf("sum = %d", sum)
+	}
+	if tok, err := dec.Token(); err != nil || tok != json.Delim(']') {
+		t.Fatalf("Token: %v, %v", tok, err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		t.Fatalf("want io.EOF, got %v", err)
+	}
+
+	dec = NewDecoder(strings.NewReader(`{"name": "x", /* c */ "other": 1}`))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&struct{ Name string }{}); err == nil || !strings.Contains(err.Error(), "unknown field") {
+		t.Fatalf("want unknown field error, got %v", err)
+	}
+}
+
+func TestParseFromReaderError(t *testing.T) {
+	padding := strings.Repeat("/* padding\n */ # padding\n", 1000)
+	data := "{\n" + padding + "\t\"a\": 1, # c\n\t\"b\": 2 x\n}"
+	err := ParseFromReader(strings.NewReader(data), &map[string]interface{}{}, 0)
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("want *SyntaxError, got %T: %v", err, err)
+	}
+	if serr.Line != 3+strings.Count(padding, "\n") || serr.Column != 9 || serr.Offset != strings.Index(data, "x") {
+		t.Fatalf("wrong position: %s", serr)
+	}
+
+	cases := map[string]string{
+		"":                 "line 1 column 1: unexpected end of JSON input",
+		"{\"a\": [1,\n2":   "line 2 column 2: unexpected end of JSON input",
+		"{} // c\n {}":     "line 2 column 2: invalid character '{' after top-level value",
+		"{} # c\n x":       "line 2 column 2: invalid character 'x'",
+		"[1,\n/* 2 */ ]\n": "line 2 column 9: invalid character ']'",
+	}
+	for data, msg := range cases {
+		err := ParseFromReader(strings.NewReader(data), &map[string]interface{}{}, 0)
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("%q: want error contains %q, got: %v", data, msg, err)
+		}
+	}
+}
+
+func largeTestData() []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteString("[\n")
+	for i := 0; buf.Len() < 10*WriteBufSize; i++ {
+		if i > 0 {
+			buf.WriteString(",\n")
+		}
+		buf.WriteString(testCases["sharpcmt"])
+	}
+	buf.WriteString("]\n")
+	return buf.Bytes()
+}
+
+// BenchmarkParseLargeFromReader decode while reading
+func BenchmarkParseLargeFromReader(b *testing.B) {
+	data := largeTestData()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var v []testData
+		if err := ParseFromReader(bytes.NewReader(data), &v, len(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkParseLargeTempFile the former path: write the stripped data into
+// a temp file, read it back into memory, then json.Unmarshal
+func BenchmarkParseLargeTempFile(b *testing.B) {
+	data := largeTestData()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tmpfile, err := ioutil.TempFile("", "BenchmarkParseLargeTempFile")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err = io.Copy(tmpfile, NewReader(bytes.NewReader(data))); err != nil {
+			b.Fatal(err)
+		}
+		if _, err = tmpfile.Seek(0, io.SeekStart); err != nil {
+			b.Fatal(err)
+		}
+		d, err := ioutil.ReadAll(tmpfile)
+		if err != nil {
+			b.Fatal(err)
+		}
+		tmpfile.Close()
+		os.Remove(tmpfile.Name())
+		var v []testData
+		if err = json.Unmarshal(d, &v); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
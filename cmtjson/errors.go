@@ -0,0 +1,141 @@
+package cmtjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// maxExcerptWidth limit the excerpt of a long line, e.g. a minified json
+const maxExcerptWidth = 80
+
+// SyntaxError is a json syntax error with the position in the commented source
+type SyntaxError struct {
+	Filename string // empty if not parsed from a file
+	Offset   int    // byte offset of the error in the source
+	Line     int    // start from 1
+	Column   int    // start from 1, counts bytes
+	Excerpt  string // the source line and a caret line under the error column, empty if the source is not kept
+	Msg      string
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Filename != "" {
+		return fmt.Sprintf("cmtjson: %s:%d:%d: %s", e.Filename, e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("cmtjson: line %d column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// newSyntaxError locate offset in src and build the error
+func newSyntaxError(src []byte, offset int, msg string) *SyntaxError {
+	e := &SyntaxError{Offset: offset, Msg: msg}
+	e.setSource(src)
+	return e
+}
+
+// setSource compute the line, column and excerpt of e.Offset in src
+func (e *SyntaxError) setSource(src []byte) {
+	offset := e.Offset
+	if offset > len(src) {
+		offset = len(src)
+	}
+	start := bytes.LastIndexByte(src[:offset], btLineBreak) + 1
+	end := bytes.IndexByte(src[offset:], btLineBreak)
+	if end < 0 {
+		end = len(src)
+	} else {
+		end += offset
+	}
+	e.Line = bytes.Count(src[:start], []byte{btLineBreak}) + 1
+	e.Column = offset - start + 1
+
+	line := bytes.TrimRight(src[start:end], "\r")
+	col := offset - start
+	prefix, suffix := "", ""
+	if len(line) > maxExcerptWidth {
+		from := col - maxExcerptWidth/2
+		if from < 0 {
+			from = 0
+		}
+		to := from + maxExcerptWidth
+		if to > len(line) {
+			to, from = len(line), len(line)-maxExcerptWidth
+		}
+		if from > 0 {
+			prefix = "..."
+		}
+		if to < len(line) {
+			suffix = "..."
+		}
+		line, col = line[from:to], col-from
+	}
+	if col > len(line) {
+		col = len(line)
+	}
+	// keep the tabs so the caret is aligned
+	caret := bytes.Map(func(r rune) rune {
+		if r == '\t' {
+			return r
+		}
+		return ' '
+	}, line[:col])
+	e.Excerpt = prefix + string(line) + suffix + "\n" + strings.Repeat(" ", len(prefix)) + string(caret) + "^"
+}
+
+// syntaxError convert a *json.SyntaxError of the stripped src into a *SyntaxError,
+// src must keep the offsets of the source, other errors are returned as is
+func syntaxError(err error, src []byte) error {
+	jerr, ok := err.(*json.SyntaxError)
+	if !ok {
+		return err
+	}
+	return newSyntaxError(src, errorOffset(jerr, len(src)), jerr.Error())
+}
+
+// errorOffset return the offset of the byte caused the error,
+// json.SyntaxError.Offset is after it
+func errorOffset(jerr *json.SyntaxError, size int) int {
+	offset := int(jerr.Offset)
+	if offset > 0 && offset <= size && jerr.Error() != "unexpected end of JSON input" {
+		offset--
+	}
+	return offset
+}
+
+// syntaxError build a *SyntaxError at offset of the data read,
+// the excerpt is not available as the data is not kept
+func (r *reader) syntaxError(offset int, msg string) *SyntaxError {
+	return r.locate(&SyntaxError{Offset: offset, Msg: msg})
+}
+
+// locate set the line and column of e from the line starts read
+func (r *reader) locate(e *SyntaxError) *SyntaxError {
+	line := sort.SearchInts(r.lines, e.Offset+1) // the first line start after offset
+	e.Line, e.Column = line, e.Offset-r.lines[line-1]+1
+	return e
+}
+
+// convertError convert a *json.SyntaxError of the data read into a *SyntaxError
+func (r *reader) convertError(err error) error {
+	if jerr, ok := err.(*json.SyntaxError); ok {
+		return r.syntaxError(errorOffset(jerr, r.offset), jerr.Error())
+	}
+	return err
+}
+
+// fileSyntaxError set the filename of a *SyntaxError and the excerpt from the original file,
+// other errors are returned as is
+func fileSyntaxError(err error, filename string) error {
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		return err
+	}
+	serr.Filename = filename
+	if src, rerr := ioutil.ReadFile(filename); rerr == nil {
+		serr.setSource(src)
+	}
+	return serr
+}
@@ -0,0 +1,111 @@
+package cmtjson
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Format print the document re-indented with indent, one member or element per line,
+// like json.Indent but the comments are kept: the leading comments on their own lines
+// and the trailing comments after the value on the same line
+func (d *Document) Format(indent string) []byte {
+	f := &formatter{indent: indent}
+	if d.Root != nil {
+		f.comments(d.Root.Comments, 0)
+		f.node(d.Root, 0)
+		f.trailing(d.Root.TrailingComments, 0)
+		f.buf.WriteByte('\n')
+	}
+	f.comments(d.Comments, 0)
+	return f.buf.Bytes()
+}
+
+type formatter struct {
+	buf    bytes.Buffer
+	indent string
+}
+
+func (f *formatter) newline(depth int) {
+	f.buf.WriteByte('\n')
+	f.buf.WriteString(strings.Repeat(f.indent, depth))
+}
+
+// comments print each comment on its own line at depth
+func (f *formatter) comments(cmts []Comment, depth int) {
+	for _, c := range cmts {
+		f.buf.WriteString(strings.Repeat(f.indent, depth))
+		f.buf.WriteString(c.Text)
+		f.buf.WriteByte('\n')
+	}
+}
+
+// trailing print the comments after a value on the same line,
+// a comment after a line comment is moved to a new line at depth
+func (f *formatter) trailing(cmts []Comment, depth int) {
+	for i, c := range cmts {
+		if i > 0 && cmts[i-1].Kind != BlockComment {
+			f.newline(depth)
+		} else {
+			f.buf.WriteByte(' ')
+		}
+		f.buf.WriteString(c.Text)
+	}
+}
+
+// items print the lines of the members or elements, each line start at depth
+func (f *formatter) items(n *Node, depth int) {
+	count := len(n.Members) + len(n.Elems)
+	for i := 0; i < count; i++ {
+		var v *Node
+		var cmts []Comment
+		key := ""
+		if n.Kind == ObjectNode {
+			m := n.Members[i]
+			v, key = m.Value, m.KeyRaw
+			// the comments between ":" and the value are moved before the key
+			cmts = append(append(cmts, m.Comments...), v.Comments...)
+		} else {
+			v = n.Elems[i]
+			cmts = v.Comments
+		}
+		f.buf.WriteByte('\n')
+		f.comments(cmts, depth)
+		f.buf.WriteString(strings.Repeat(f.indent, depth))
+		if n.Kind == ObjectNode {
+			f.buf.WriteString(key)
+			f.buf.WriteString(": ")
+		}
+		f.node(v, depth)
+		if i < count-1 {
+			f.buf.WriteByte(',')
+		}
+		f.trailing(v.TrailingComments, depth)
+	}
+}
+
+func (f *formatter) node(n *Node, depth int) {
+	var open, end byte
+	switch n.Kind {
+	case ObjectNode:
+		open, end = '{', '}'
+	case ArrayNode:
+		open, end = '[', ']'
+	default:
+		f.buf.WriteString(n.Raw)
+		return
+	}
+	f.buf.WriteByte(open)
+	if len(n.Members)+len(n.Elems)+len(n.EndComments) == 0 {
+		f.buf.WriteByte(end)
+		return
+	}
+	f.items(n, depth+1)
+	if len(n.EndComments) > 0 {
+		f.buf.WriteByte('\n')
+		f.comments(n.EndComments, depth+1)
+		f.buf.WriteString(strings.Repeat(f.indent, depth))
+	} else {
+		f.newline(depth)
+	}
+	f.buf.WriteByte(end)
+}
@@ -0,0 +1,106 @@
+package cmtjson
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDocumentFormat(t *testing.T) {
+	src := `# app config
+{"name":"app", // the name
+  "db" :{ /* primary */ "host":"localhost","ports":[1,2 ,3]},
+  "empty":{},"list":[
+    // nothing yet
+  ]}
+// end`
+	want := `# app config
+{
+  "name": "app", // the name
+  "db": {
+    /* primary */
+    "host": "localhost",
+    "ports": [
+      1,
+      2,
+      3
+    ]
+  },
+  "empty": {},
+  "list": [
+    // nothing yet
+  ]
+}
+// end
+`
+	doc, err := ParseAST([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out := string(doc.Format("  ")); out != want {
+		t.Fatalf("Format() =\n%s\nwant:\n%s", out, want)
+	}
+
+	for caseName, cs := range testCases {
+		doc, err := ParseAST([]byte(cs))
+		if err != nil {
+			t.Fatalf("case: %s => parse error: %s", caseName, err)
+		}
+		out := doc.Format("\t")
+		var v, want interface{}
+		// ParseFromBytes strip the comments of out in place
+		if err = ParseFromBytes(append([]byte(nil), out...), &v); err != nil {
+			t.Fatalf("case: %s => formatted not parsed: %s\n%s", caseName, err, out)
+		}
+		if err = ParseFromBytes([]byte(cs), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Fatalf("case: %s => formatted value changed:\n%s", caseName, out)
+		}
+		again, err := ParseAST(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out2 := again.Format("\t"); string(out2) != string(out) {
+			t.Fatalf("case: %s => Format not idempotent:\n%s\n---\n%s", caseName, out, out2)
+		}
+	}
+}
+
+// the comments before a "," on the other lines are not trailing ones,
+// the formatted document must parse to the same value
+func TestDocumentFormatComments(t *testing.T) {
+	src := "{\"a\": 1 # x\n /* y\n z */, \"b\": [2 // two\n // after two\n , 3 /* c */ // d\n]}"
+	want := "{\n  \"a\": 1, # x\n  /* y\n z */\n  \"b\": [\n    2, // two\n    // after two\n    3 /* c */ // d\n  ]\n}\n"
+	doc, err := ParseAST([]byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := doc.Format("  ")
+	if string(out) != want {
+		t.Fatalf("Format() =\n%s\nwant:\n%s", out, want)
+	}
+
+	for _, src := range []string{
+		src,
+		"[1 /* a\n b */, 2]",
+		"{\"a\": {} // x\n , \"b\": null # y\n}",
+		"[1, /* a */ /* b */ 2 // c\n]",
+	} {
+		doc, err := ParseAST([]byte(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := doc.Format("\t")
+		var v, want interface{}
+		if err = ParseFromBytes(append([]byte(nil), out...), &v); err != nil {
+			t.Fatalf("%q formatted not parsed: %s\n%s", src, err, out)
+		}
+		if err = ParseFromBytes([]byte(src), &want); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Fatalf("%q formatted value changed:\n%s", src, out)
+		}
+	}
+}
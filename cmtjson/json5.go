@@ -0,0 +1,401 @@
+package cmtjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// json5 translate the relaxed json (with comments) into plain json:
+//
+//	{
+//		unquoted: 'single quoted',  // unquoted keys and single quoted strings
+//		hex: 0xFF, half: .5,        // hexadecimal numbers, leading or trailing decimal point
+//		inf: +Infinity, nan: NaN,   // become the strings "Infinity" and "NaN"
+//		lines: "line 1 \
+//	line 2",                        // multi-line strings
+//		list: [1, 2, ],             // trailing commas
+//	}
+type json5 struct {
+	src []byte
+	off int
+	out []byte
+	o   Options
+
+	comma int       // the out offset of the last "," not followed by a value yet, -1 if none
+	open  bool      // just after a "{" or "[", no value in it yet
+	segs  []segment // map the out offsets back to the src offsets
+}
+
+// segment is a piece of out starting at out from src
+type segment struct {
+	out, src int
+}
+
+// translateJSON5 return the plain json and the offset map of src
+func translateJSON5(src []byte, o Options) ([]byte, *json5, error) {
+	t := &json5{src: src, out: make([]byte, 0, len(src)), o: o, comma: -1}
+	for t.off < len(t.src) {
+		if err := t.next(); err != nil {
+			return nil, nil, err
+		}
+	}
+	return t.out, t, nil
+}
+
+// srcOffset map an offset of out to src
+func (t *json5) srcOffset(off int) int {
+	i := sort.Search(len(t.segs), func(i int) bool { return t.segs[i].out > off }) - 1
+	if i < 0 {
+		return off
+	}
+	seg := t.segs[i]
+	src := seg.src + off - seg.out
+	// a translated token is shorter or longer than its source
+	if i+1 < len(t.segs) && src > t.segs[i+1].src {
+		src = t.segs[i+1].src
+	}
+	if src > len(t.src) {
+		src = len(t.src)
+	}
+	return src
+}
+
+func (t *json5) errorf(format string, args ...interface{}) error {
+	return newSyntaxError(t.src, t.off, fmt.Sprintf(format, args...))
+}
+
+// emit write s as the translation of src[t.off:t.off+n]
+func (t *json5) emit(s string, n int) {
+	t.segs = append(t.segs, segment{out: len(t.out), src: t.off})
+	t.out = append(t.out, s...)
+	t.off += n
+}
+
+// value mark the start of a value or a key, the pending "," is not a trailing one
+func (t *json5) value() {
+	t.comma = -1
+	t.open = false
+}
+
+func (t *json5) next() error {
+	c := t.src[t.off]
+	switch {
+	case c == ' ' || c == '\t' || c == '\r' || c == btLineBreak:
+		t.emit(string(c), 1)
+	case c == '\v' || c == '\f':
+		t.emit(" ", 1)
+	case c == btSharp || (c == btSlash && t.peek(1) == btSlash):
+		if c == btSharp && !t.o.allowed(SharpComment) {
+			return t.errorf("# comments are not allowed")
+		}
+		if c == btSlash && !t.o.allowed(SlashComment) {
+			return t.errorf("// comments are not allowed")
+		}
+		end := bytes.IndexByte(t.src[t.off:], btLineBreak)
+		if end < 0 {
+			end = len(t.src) - t.off
+		}
+		t.emit(strings.Repeat(" ", end), end)
+	case c == btSlash && t.peek(1) == btStar:
+		if !t.o.allowed(BlockComment) {
+			return t.errorf("/* */ comments are not allowed")
+		}
+		end := blockCommentEnd(t.src[t.off:], t.o.NestedBlockComments)
+		if end < 0 {
+			if t.o.ErrorOnUnterminatedComment {
+				return t.errorf("unterminated block comment")
+			}
+			end = len(t.src) - t.off
+		}
+		t.emit(blank(t.src[t.off:t.off+end]), end)
+	case c == ',':
+		// only a value can be followed by a comma, not "[,]" or "{, a: 1}"
+		if t.open {
+			return t.errorf("unexpected comma")
+		}
+		t.comma = len(t.out)
+		t.emit(",", 1)
+	case c == '{' || c == '[':
+		// a value after the pending ",", the "," is not a trailing one
+		t.value()
+		t.open = true
+		t.emit(string(c), 1)
+	case c == '}' || c == ']':
+		// drop the trailing comma
+		if t.comma >= 0 {
+			t.out[t.comma] = btSpace
+			t.comma = -1
+		}
+		t.open = false
+		t.emit(string(c), 1)
+	case c == btDbQuote || c == '\'':
+		t.value()
+		return t.str(c)
+	case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+		t.value()
+		return t.number()
+	case c == '_' || c == '$' || c == btBackslash || c >= utf8.RuneSelf || unicode.IsLetter(rune(c)):
+		t.value()
+		return t.ident()
+	default:
+		// let encoding/json report the error
+		t.emit(string(c), 1)
+	}
+	return nil
+}
+
+func (t *json5) peek(n int) byte {
+	if t.off+n < len(t.src) {
+		return t.src[t.off+n]
+	}
+	return 0
+}
+
+// blockCommentEnd return the length of the block comment at the start of b, -1 if unterminated
+func blockCommentEnd(b []byte, nested bool) int {
+	depth := 0
+	for i := 0; i+1 < len(b); i++ {
+		if b[i] == btSlash && b[i+1] == btStar && (depth == 0 || nested) {
+			depth++
+			i++
+		} else if b[i] == btStar && b[i+1] == btSlash {
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
+// blank replace the bytes of b with spaces, line breaks are kept
+func blank(b []byte) string {
+	s := []byte(strings.Repeat(" ", len(b)))
+	for i := range b {
+		if b[i] == btLineBreak {
+			s[i] = btLineBreak
+		}
+	}
+	return string(s)
+}
+
+// str translate a double or single quoted string
+func (t *json5) str(quote byte) error {
+	var sb strings.Builder
+	i := t.off + 1
+	for {
+		if i >= len(t.src) {
+			return t.errorf("unterminated string")
+		}
+		c := t.src[i]
+		if c == quote {
+			break
+		}
+		if c == btLineBreak || c == '\r' {
+			t.off = i
+			return t.errorf("invalid line break in string, use \"\\\" at the end of line for multi-line strings")
+		}
+		if c != btBackslash {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+		i++
+		if i >= len(t.src) {
+			return t.errorf("unterminated string")
+		}
+		switch e := t.src[i]; e {
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'n':
+			sb.WriteByte('\n')
+		case 'r':
+			sb.WriteByte('\r')
+		case 't':
+			sb.WriteByte('\t')
+		case 'v':
+			sb.WriteByte('\v')
+		case '0':
+			sb.WriteByte(0)
+		case 'x', 'u':
+			n := 2
+			if e == 'u' {
+				n = 4
+			}
+			if i+n >= len(t.src) {
+				return t.errorf("unterminated string")
+			}
+			r, err := strconv.ParseUint(string(t.src[i+1:i+1+n]), 16, 32)
+			if err != nil {
+				t.off = i - 1
+				return t.errorf("invalid escape \\%c%s", e, t.src[i+1:i+1+n])
+			}
+			i += n
+			// a surrogate pair
+			if r >= 0xD800 && r < 0xDC00 && i+6 < len(t.src) && t.src[i+1] == btBackslash && t.src[i+2] == 'u' {
+				if r2, err := strconv.ParseUint(string(t.src[i+3:i+7]), 16, 32); err == nil && r2 >= 0xDC00 && r2 < 0xE000 {
+					r = (r-0xD800)<<10 + (r2 - 0xDC00) + 0x10000
+					i += 6
+				}
+			}
+			sb.WriteRune(rune(r))
+		case '\r':
+			// line continuation
+			if i+1 < len(t.src) && t.src[i+1] == btLineBreak {
+				i++
+			}
+		case btLineBreak:
+			// line continuation
+		default:
+			sb.WriteByte(e)
+		}
+		i++
+	}
+	data, err := json.Marshal(sb.String())
+	if err != nil {
+		return t.errorf("invalid string: %s", err)
+	}
+	t.emit(string(data), i+1-t.off)
+	return nil
+}
+
+// number translate the hexadecimal numbers, Infinity, NaN and the decimal point forms
+func (t *json5) number() error {
+	i := t.off
+	sign := ""
+	if c := t.src[i]; c == '-' || c == '+' {
+		if c == '-' {
+			sign = "-"
+		}
+		i++
+	}
+	rest := t.src[i:]
+	switch {
+	case bytes.HasPrefix(rest, []byte("Infinity")):
+		t.emit(`"`+sign+`Infinity"`, i-t.off+len("Infinity"))
+		return nil
+	case bytes.HasPrefix(rest, []byte("NaN")):
+		t.emit(`"NaN"`, i-t.off+len("NaN"))
+		return nil
+	}
+
+	j := i
+	for j < len(t.src) && isNumberByte(t.src[j]) {
+		j++
+	}
+	lit := string(t.src[i:j])
+	if lit == "" {
+		t.emit(string(t.src[t.off]), 1)
+		return nil
+	}
+	if strings.HasPrefix(lit, "0x") || strings.HasPrefix(lit, "0X") {
+		n, err := strconv.ParseUint(lit[2:], 16, 64)
+		if err != nil {
+			return t.errorf("invalid hexadecimal number %s", lit)
+		}
+		t.emit(sign+strconv.FormatUint(n, 10), j-t.off)
+		return nil
+	}
+	// the integer part or the fraction must have a digit, not ".", "+." or ".e3"
+	mantissa := lit
+	if k := strings.IndexAny(lit, "eE"); k >= 0 {
+		mantissa = lit[:k]
+	}
+	if !strings.ContainsAny(mantissa, "0123456789") {
+		return t.errorf("invalid number %s", t.src[t.off:j])
+	}
+	// ".5" -> "0.5", "5." -> "5", "5.e3" -> "5e3"
+	if strings.HasPrefix(lit, ".") {
+		lit = "0" + lit
+	}
+	if k := strings.IndexByte(lit, '.'); k >= 0 && (k == len(lit)-1 || lit[k+1] == 'e' || lit[k+1] == 'E') {
+		lit = lit[:k] + lit[k+1:]
+	}
+	t.emit(sign+lit, j-t.off)
+	return nil
+}
+
+func isNumberByte(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F') ||
+		c == 'x' || c == 'X' || c == '.' || c == '+' || c == '-'
+}
+
+// ident translate an identifier: a literal, Infinity, NaN or an unquoted key
+func (t *json5) ident() error {
+	var sb strings.Builder
+	i := t.off
+	for i < len(t.src) {
+		r, size := utf8.DecodeRune(t.src[i:])
+		if r == '\\' {
+			// an unicode escape in the identifier
+			if i+5 >= len(t.src) || t.src[i+1] != 'u' {
+				t.off = i
+				return t.errorf("invalid escape in identifier")
+			}
+			n, err := strconv.ParseUint(string(t.src[i+2:i+6]), 16, 32)
+			if err != nil {
+				t.off = i
+				return t.errorf("invalid escape in identifier")
+			}
+			r, size = rune(n), 6
+		} else if !(r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r) ||
+			unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r) || unicode.Is(unicode.Pc, r) ||
+			r == '\u200c' || r == '\u200d') {
+			break
+		}
+		sb.WriteRune(r)
+		i += size
+	}
+	id := sb.String()
+	if id == "" {
+		return t.errorf("invalid character %q", t.src[t.off])
+	}
+	if t.isKey(i) {
+		data, _ := json.Marshal(id)
+		t.emit(string(data), i-t.off)
+		return nil
+	}
+	switch id {
+	case "true", "false", "null":
+		t.emit(id, i-t.off)
+	case "Infinity", "NaN":
+		t.emit(`"`+id+`"`, i-t.off)
+	default:
+		return t.errorf("invalid identifier %s", id)
+	}
+	return nil
+}
+
+// isKey report whether the next token after i is ":"
+func (t *json5) isKey(i int) bool {
+	for i < len(t.src) {
+		switch c := t.src[i]; {
+		case c == ' ' || c == '\t' || c == '\r' || c == btLineBreak || c == '\v' || c == '\f':
+			i++
+		case c == btSlash && i+1 < len(t.src) && t.src[i+1] == btStar:
+			end := blockCommentEnd(t.src[i:], t.o.NestedBlockComments)
+			if end < 0 {
+				return false
+			}
+			i += end
+		case c == btSharp || (c == btSlash && i+1 < len(t.src) && t.src[i+1] == btSlash):
+			end := bytes.IndexByte(t.src[i:], btLineBreak)
+			if end < 0 {
+				return false
+			}
+			i += end
+		default:
+			return c == ':'
+		}
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package cmtjson
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseJSON5(t *testing.T) {
+	data := `// json5 with comments
+	{
+		unquoted: 'single "quoted"', # hash comment
+		$dollar_1: "\x41é\'",
+		hex: 0xFF, neg: -0x10, half: .5, whole: 5., exp: 1.e2, plus: +3,
+		inf: +Infinity, ninf: -Infinity, nan: NaN,
+		lines: "line 1 \
+line 2",
+		list: [1, 2, /* c */ ],
+		obj: {a: null, b: true,},
+		'quoted key': 'it\'s',
+	}`
+	var v map[string]interface{}
+	if err := ParseFromBytes([]byte(data), &v, Options{JSON5: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"unquoted":   `single "quoted"`,
+		"$dollar_1":  "Aé'",
+		"hex":        255.0,
+		"neg":        -16.0,
+		"half":       0.5,
+		"whole":      5.0,
+		"exp":        100.0,
+		"plus":       3.0,
+		"inf":        "Infinity",
+		"ninf":       "-Infinity",
+		"nan":        "NaN",
+		"lines":      "line 1 line 2",
+		"list":       []interface{}{1.0, 2.0},
+		"obj":        map[string]interface{}{"a": nil, "b": true},
+		"quoted key": "it's",
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("got:\n%#v\nwant:\n%#v", v, want)
+	}
+	if f, err := strconv.ParseFloat(v["ninf"].(string), 64); err != nil || f > 0 {
+		t.Fatalf("ParseFloat(%q) = %v, %v", v["ninf"], f, err)
+	}
+
+	// the default mode stays strict
+	if err := ParseFromBytes([]byte(`{a: 1}`), &v); err == nil {
+		t.Fatal("unquoted key should fail without JSON5")
+	}
+	// the reader path
+	v = nil
+	if err := ParseFromReader(strings.NewReader(`[0x1, 'a',]`), &v, 0, Options{JSON5: true}); err == nil {
+		t.Fatal("want unmarshal error into map")
+	}
+	var s []interface{}
+	if err := ParseFromReader(strings.NewReader(`[0x1, 'a',]`), &s, 0, Options{JSON5: true}); err != nil || !reflect.DeepEqual(s, []interface{}{1.0, "a"}) {
+		t.Fatalf("got %#v, %v", s, err)
+	}
+}
+
+// a container after "," is a value, the "," is not a trailing one
+func TestParseJSON5Containers(t *testing.T) {
+	cases := map[string]interface{}{
+		`[1, {}]`:                     []interface{}{1.0, map[string]interface{}{}},
+		`[{}, {}]`:                    []interface{}{map[string]interface{}{}, map[string]interface{}{}},
+		`{a: [[1], []]}`:              map[string]interface{}{"a": []interface{}{[]interface{}{1.0}, []interface{}{}}},
+		`[[1], [2,], ]`:               []interface{}{[]interface{}{1.0}, []interface{}{2.0}},
+		`{a: {}, b: {c: [1, [],],},}`: map[string]interface{}{"a": map[string]interface{}{}, "b": map[string]interface{}{"c": []interface{}{1.0, []interface{}{}}}},
+	}
+	for data, want := range cases {
+		var v interface{}
+		if err := ParseFromBytes([]byte(data), &v, Options{JSON5: true}); err != nil {
+			t.Fatalf("%s: %s", data, err)
+		}
+		if !reflect.DeepEqual(v, want) {
+			t.Fatalf("%s: got %#v", data, v)
+		}
+	}
+}
+
+func TestParseJSON5Error(t *testing.T) {
+	cases := map[string]string{
+		"{a: 1,\n b: tru}":         "line 2 column 5: invalid identifier tru",
+		"{a: 'x\n'}":               "line 1 column 7: invalid line break in string",
+		"{a: 'x}":                  "line 1 column 5: unterminated string",
+		"{a: 0xZZ}":                "line 1 column 5: invalid hexadecimal number",
+		"{\n  long_key: 1 2}":      "line 2 column 15: invalid character '2'",
+		"{'a': [1,, 2]}":           "line 1 column 10: invalid character ','",
+		"/* c */ {key: 'value' x}": "line 1 column 23: invalid identifier x",
+		"{a: .}":                   "line 1 column 5: invalid number .",
+		"[+.]":                     "line 1 column 2: invalid number +.",
+		"[-., 1]":                  "line 1 column 2: invalid number -.",
+		"{a: .e3}":                 "line 1 column 5: invalid number .e3",
+		"[ , ]":                    "line 1 column 3: unexpected comma",
+		"{ , }":                    "line 1 column 3: unexpected comma",
+		"[[,], 1]":                 "line 1 column 3: unexpected comma",
+		"{a: [1], b: {, c: 1}}":    "line 1 column 14: unexpected comma",
+		"[1,,]":                    "invalid character",
+	}
+	for data, msg := range cases {
+		var v interface{}
+		err := ParseFromBytes([]byte(data), &v, Options{JSON5: true})
+		if _, ok := err.(*SyntaxError); !ok || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("%q: want *SyntaxError contains %q, got: %T %v", data, msg, err, err)
+		}
+	}
+}
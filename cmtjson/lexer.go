@@ -0,0 +1,184 @@
+package cmtjson
+
+// lexState is the state of the stripper lexer
+type lexState int
+
+// lexer states
+const (
+	lexCode       lexState = iota // json tokens
+	lexSlash                      // a "/" in code, may start a comment
+	lexString                     // in a json string
+	lexEscape                     // after a "\" in a string
+	lexUnicode                    // in the hex digits of a "\uXXXX" escape
+	lexLineCmt                    // in a "#" or "//" comment
+	lexBlockCmt                   // in a "/* */" comment
+	lexBlockStar                  // a "*" in a block comment, may end it
+	lexBlockSlash                 // a "/" in a block comment, may start a nested one
+)
+
+// stripper is the lexer to remove comments, it is fed byte by byte
+// and output one byte for each input byte, comments are replaced with spaces,
+// line breaks are kept so the offsets and lines of the source not change.
+// the output is one byte behind, as a "/" is known to start a comment only
+// after the next byte
+type stripper struct {
+	state    lexState
+	hex      int // hex digits left in a "\uXXXX" escape
+	depth    int // depth of the nested block comments
+	cmtStart int // offset of the block comment start
+
+	prev     byte // the byte not output yet
+	prevKeep bool // whether prev is kept, or is a part of a comment
+	started  bool
+
+	sharp, slash, block, nested, strict bool // from Options
+
+	off int // offset of the byte fed
+	err *SyntaxError
+}
+
+func newStripper(o Options) *stripper {
+	s := &stripper{nested: o.NestedBlockComments, strict: o.ErrorOnUnterminatedComment}
+	s.sharp, s.slash, s.block = o.allowed(SharpComment), o.allowed(SlashComment), o.allowed(BlockComment)
+	return s
+}
+
+// step feed c, return the output of the previous byte,
+// ok is false for the first byte as there is no previous one.
+// a disallowed comment set s.err, the output after it is undefined
+func (s *stripper) step(c byte) (out byte, ok bool) {
+	keep := s.lex(c)
+	out, ok = stripped(s.prev, s.prevKeep), s.started
+	s.prev, s.prevKeep, s.started = c, keep, true
+	s.off++
+	return
+}
+
+// lex move the state by c, return whether c is kept
+func (s *stripper) lex(c byte) bool {
+	switch s.state {
+	case lexSlash:
+		switch c {
+		case btSlash:
+			s.prevKeep = false
+			s.state = lexLineCmt
+			s.check(s.slash, s.off-1, "// comments are not allowed")
+			return false
+		case btStar:
+			s.prevKeep = false
+			s.state, s.depth, s.cmtStart = lexBlockCmt, 1, s.off-1
+			s.check(s.block, s.off-1, "/* */ comments are not allowed")
+			return false
+		}
+		// not a comment, the "/" is kept for encoding/json to report
+		s.state = lexCode
+		return s.lex(c)
+	case lexString:
+		switch c {
+		case btDbQuote:
+			s.state = lexCode
+		case btBackslash:
+			s.state = lexEscape
+		}
+		return true
+	case lexEscape:
+		if c == 'u' {
+			s.state, s.hex = lexUnicode, 4
+		} else {
+			s.state = lexString
+		}
+		return true
+	case lexUnicode:
+		if !isHex(c) {
+			// an invalid escape, encoding/json report it
+			s.state = lexString
+			return s.lex(c)
+		}
+		if s.hex--; s.hex == 0 {
+			s.state = lexString
+		}
+		return true
+	case lexLineCmt:
+		if c == btLineBreak {
+			s.state = lexCode
+			return true
+		}
+		return false
+	case lexBlockCmt:
+		switch {
+		case c == btStar:
+			s.state = lexBlockStar
+		case c == btSlash && s.nested:
+			s.state = lexBlockSlash
+		}
+		return false
+	case lexBlockStar:
+		switch c {
+		case btSlash:
+			if s.depth--; s.depth == 0 {
+				s.state = lexCode
+			} else {
+				s.state = lexBlockCmt
+			}
+		case btStar:
+		default:
+			s.state = lexBlockCmt
+			return s.lex(c)
+		}
+		return false
+	case lexBlockSlash:
+		switch c {
+		case btStar:
+			s.depth++
+			s.state = lexBlockCmt
+		case btSlash:
+		default:
+			s.state = lexBlockCmt
+			return s.lex(c)
+		}
+		return false
+	}
+
+	// lexCode
+	switch c {
+	case btDbQuote:
+		s.state = lexString
+	case btSharp:
+		s.state = lexLineCmt
+		s.check(s.sharp, s.off, "# comments are not allowed")
+		return false
+	case btSlash:
+		s.state = lexSlash
+	}
+	return true
+}
+
+func isHex(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// check set the error at offset if the comment is not allowed
+func (s *stripper) check(allowed bool, offset int, msg string) {
+	if !allowed && s.err == nil {
+		s.err = &SyntaxError{Offset: offset, Msg: msg}
+	}
+}
+
+// flush return the output of the last byte, ok is false if nothing was fed
+func (s *stripper) flush() (byte, bool) {
+	switch s.state {
+	case lexBlockCmt, lexBlockStar, lexBlockSlash:
+		if s.strict && s.err == nil {
+			s.err = &SyntaxError{Offset: s.cmtStart, Msg: "unterminated block comment"}
+		}
+	}
+	return stripped(s.prev, s.prevKeep), s.started
+}
+
+// stripped return the byte to output for b, a line break in a comment is kept
+func stripped(b byte, keep bool) byte {
+	if keep || b == btLineBreak {
+		return b
+	}
+	return btSpace
+}
@@ -0,0 +1,124 @@
+package cmtjson
+
+import (
+	"bytes"
+	"io/ioutil"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// referenceRemoveComments is a straightforward implementation to check the lexer:
+// skip the strings as a whole, blank the comments found by searching their ends
+func referenceRemoveComments(src []byte) []byte {
+	out := append([]byte(nil), src...)
+	blank := func(from, to int) {
+		for i := from; i < to; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+	for i := 0; i < len(src); {
+		switch {
+		case src[i] == '"':
+			i++
+			for i < len(src) && src[i] != '"' {
+				if src[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i++
+		case src[i] == '#' || bytes.HasPrefix(src[i:], []byte("//")):
+			end := bytes.IndexByte(src[i:], '\n')
+			if end < 0 {
+				end = len(src) - i
+			}
+			blank(i, i+end)
+			i += end
+		case bytes.HasPrefix(src[i:], []byte("/*")):
+			end := bytes.Index(src[i+2:], []byte("*/"))
+			if end < 0 {
+				end = len(src) - i
+			} else {
+				end += 4
+			}
+			blank(i, i+end)
+			i += end
+		default:
+			i++
+		}
+	}
+	return out
+}
+
+func checkLexer(t *testing.T, src []byte) {
+	want := referenceRemoveComments(src)
+	got := RemoveJSONCommentBytes(append([]byte(nil), src...))
+	if !bytes.Equal(got, want) {
+		t.Fatalf("RemoveJSONCommentBytes(%q):\n got %q\nwant %q", src, got, want)
+	}
+	got, err := ioutil.ReadAll(NewReader(iotest.OneByteReader(bytes.NewReader(src))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("NewReader(%q):\n got %q\nwant %q", src, got, want)
+	}
+}
+
+func TestLexerEscapes(t *testing.T) {
+	data := `{
+		"path": "C:\\", // the string ends with an escaped backslash
+		"quote": "\"# not a comment", # a comment
+		"uni": "\u005c", /* "\u005c" is a backslash */
+		"slash": "\/\/ not a comment\\\\"
+	}`
+	checkLexer(t, []byte(data))
+	var v map[string]string
+	if err := ParseFromBytes([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"path": `C:\`, "quote": `"# not a comment`, "uni": `\`, "slash": `// not a comment\\`}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("got %#v, want %#v", v, want)
+	}
+}
+
+// randomJSONWithComments mix the strings with escapes and the comments with json tokens
+func randomJSONWithComments(r *rand.Rand) []byte {
+	pieces := []string{
+		`"a"`, `"\\"`, `"\""`, `"\\\""`, `"\u005c"`, `"\u00"`, `"#"`, `"//"`, `"/*"`, `"*/"`, `"\/"`,
+		`# c`, `// c`, `/* c */`, `/* * / */`, `/*/ c */`, `/**/`, `/* "c */`, `// "c`, `# "c`,
+		"\n", " ", ":", ",", "{", "}", "[", "]", "1", "/", "*", `\`, `"`,
+	}
+	var buf bytes.Buffer
+	for n := r.Intn(30); n >= 0; n-- {
+		buf.WriteString(pieces[r.Intn(len(pieces))])
+	}
+	return buf.Bytes()
+}
+
+func TestLexerRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 20000; i++ {
+		checkLexer(t, randomJSONWithComments(r))
+	}
+}
+
+func FuzzLexer(f *testing.F) {
+	for _, cs := range testCases {
+		f.Add([]byte(cs))
+	}
+	r := rand.New(rand.NewSource(2))
+	for i := 0; i < 20; i++ {
+		f.Add(randomJSONWithComments(r))
+	}
+	f.Add([]byte(strings.Repeat(`"C:\\" # c`+"\n", 3)))
+	f.Fuzz(func(t *testing.T, src []byte) {
+		checkLexer(t, src)
+	})
+}
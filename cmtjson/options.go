@@ -0,0 +1,58 @@
+package cmtjson
+
+import "encoding/json"
+
+// Options select the dialect of the parse functions,
+// the zero value is the default: json with "#", "//" and "/* ... */" comments
+type Options struct {
+	// JSON5 accept the relaxed json besides the comments: trailing commas, unquoted object keys,
+	// single quoted strings, hexadecimal numbers, leading or trailing decimal point, explicit plus sign,
+	// and multi-line strings ending the lines with "\".
+	// Infinity, -Infinity and NaN can not be represented in json, they become the strings
+	// "Infinity", "-Infinity" and "NaN", which strconv.ParseFloat understands
+	JSON5 bool
+
+	// Comments select the allowed comment styles, nil allows all of them,
+	// an empty slice allows none. a disallowed comment is a *SyntaxError
+	Comments []CommentKind
+	// NestedBlockComments allow the block comments to be nested like "/* a /* b */ c */"
+	NestedBlockComments bool
+	// ErrorOnUnterminatedComment report an unterminated block comment as a *SyntaxError
+	// instead of treating the rest of the input as comment
+	ErrorOnUnterminatedComment bool
+}
+
+// allowed report whether the comment style k is allowed
+func (o Options) allowed(k CommentKind) bool {
+	if o.Comments == nil {
+		return true
+	}
+	for _, ck := range o.Comments {
+		if ck == k {
+			return true
+		}
+	}
+	return false
+}
+
+// option return the first of opts, or the default options
+func option(opts []Options) Options {
+	if len(opts) > 0 {
+		return opts[0]
+	}
+	return Options{}
+}
+
+// parseJSON5 translate data into plain json then unmarshal it into v,
+// the syntax errors are located in data
+func parseJSON5(data []byte, v interface{}, o Options) error {
+	out, t, err := translateJSON5(data, o)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(out, v)
+	if jerr, ok := err.(*json.SyntaxError); ok {
+		return newSyntaxError(data, t.srcOffset(errorOffset(jerr, len(out))), jerr.Error())
+	}
+	return err
+}
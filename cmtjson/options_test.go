@@ -0,0 +1,59 @@
+package cmtjson
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOptionsComments(t *testing.T) {
+	slashOnly := Options{Comments: []CommentKind{SlashComment}}
+	nested := Options{NestedBlockComments: true, ErrorOnUnterminatedComment: true}
+
+	type result struct {
+		v   interface{}
+		err string
+	}
+	cases := []struct {
+		data string
+		o    Options
+		want result
+	}{
+		{"[1,# c\n2]", Options{}, result{v: []interface{}{1.0, 2.0}}},
+		{"[1, // c\n2]", slashOnly, result{v: []interface{}{1.0, 2.0}}},
+		{"[1,\n # c\n2]", slashOnly, result{err: "line 2 column 2: # comments are not allowed"}},
+		{"[1, /* c */ 2]", slashOnly, result{err: "line 1 column 5: /* */ comments are not allowed"}},
+		{"[1, // c\n2]", Options{Comments: []CommentKind{}}, result{err: "line 1 column 5: // comments are not allowed"}},
+		{"[1, /* a /* b */ c */ 2]", nested, result{v: []interface{}{1.0, 2.0}}},
+		{"[1, /*/ a */ 2]", Options{}, result{v: []interface{}{1.0, 2.0}}},
+		{"[1, /* a /* b */ 2]", Options{}, result{v: []interface{}{1.0, 2.0}}},
+		{"[1, 2] /* a /* b */", nested, result{err: "line 1 column 8: unterminated block comment"}},
+		{"[1, 2] /* a", Options{}, result{v: []interface{}{1.0, 2.0}}},
+		{"[1, 2]\n/* a", Options{ErrorOnUnterminatedComment: true}, result{err: "line 2 column 1: unterminated block comment"}},
+	}
+	for _, cs := range cases {
+		for _, json5 := range []bool{false, true} {
+			o := cs.o
+			o.JSON5 = json5
+			parsers := map[string]func(v interface{}) error{
+				"bytes": func(v interface{}) error { return ParseFromBytes([]byte(cs.data), v, o) },
+				"reader": func(v interface{}) error {
+					return ParseFromReader(strings.NewReader(cs.data), v, 0, o)
+				},
+			}
+			for name, parse := range parsers {
+				var v interface{}
+				err := parse(&v)
+				if cs.want.err != "" {
+					if _, ok := err.(*SyntaxError); !ok || !strings.Contains(err.Error(), cs.want.err) {
+						t.Fatalf("%s json5=%v %q: want *SyntaxError contains %q, got: %v", name, json5, cs.data, cs.want.err, err)
+					}
+					continue
+				}
+				if err != nil || !reflect.DeepEqual(v, cs.want.v) {
+					t.Fatalf("%s json5=%v %q: got %#v, %v, want %#v", name, json5, cs.data, v, err, cs.want.v)
+				}
+			}
+		}
+	}
+}
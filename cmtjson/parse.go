@@ -0,0 +1,346 @@
+package cmtjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// ParseAST parse the json data with comment into a Document,
+// comments are attached to the nodes, whitespace is kept for printing.
+// a syntax error is returned as *SyntaxError
+func ParseAST(data []byte) (*Document, error) {
+	p := &astParser{data: data, line: 1, col: 1}
+	before, cmts, err := p.trivia()
+	if err != nil {
+		return nil, err
+	}
+	if p.off >= len(p.data) {
+		return nil, p.errorf("unexpected end of input, want a json value")
+	}
+	root, err := p.value(before, cmts)
+	if err != nil {
+		return nil, err
+	}
+	after, cmts, err := p.trivia()
+	if err != nil {
+		return nil, err
+	}
+	if p.off < len(p.data) {
+		return nil, p.errorf("unexpected %q after the root value", p.data[p.off])
+	}
+	doc := &Document{Root: root, after: after}
+	root.TrailingComments, doc.Comments = splitTrailing(cmts, root.End.Line)
+	return doc, nil
+}
+
+// ParseASTFromFile parse the file into a Document
+func ParseASTFromFile(filename string) (*Document, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := ParseAST(data)
+	if serr, ok := err.(*SyntaxError); ok {
+		serr.Filename = filename
+	}
+	return doc, err
+}
+
+type astParser struct {
+	data []byte
+	off  int
+	line int
+	col  int
+}
+
+func (p *astParser) pos() Pos {
+	return Pos{Offset: p.off, Line: p.line, Column: p.col}
+}
+
+func (p *astParser) advance(n int) {
+	for i := 0; i < n && p.off < len(p.data); i++ {
+		if p.data[p.off] == btLineBreak {
+			p.line++
+			p.col = 1
+		} else {
+			p.col++
+		}
+		p.off++
+	}
+}
+
+func (p *astParser) errorf(format string, args ...interface{}) error {
+	return newSyntaxError(p.data, p.off, fmt.Sprintf(format, args...))
+}
+
+// trivia consume the whitespace and comments
+func (p *astParser) trivia() (string, []Comment, error) {
+	start := p.off
+	var cmts []Comment
+	for p.off < len(p.data) {
+		c := p.data[p.off]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			p.advance(1)
+		case c == btSharp || (c == btSlash && p.peek(1) == btSlash):
+			cmt := Comment{Kind: SharpComment, Pos: p.pos()}
+			if c == btSlash {
+				cmt.Kind = SlashComment
+			}
+			end := bytes.IndexByte(p.data[p.off:], btLineBreak)
+			if end < 0 {
+				end = len(p.data) - p.off
+			}
+			cmt.Text = string(p.data[p.off : p.off+end])
+			p.advance(end)
+			cmts = append(cmts, cmt)
+		case c == btSlash && p.peek(1) == btStar:
+			cmt := Comment{Kind: BlockComment, Pos: p.pos()}
+			end := bytes.Index(p.data[p.off+2:], []byte("*/"))
+			if end < 0 {
+				return "", nil, p.errorf("unterminated block comment")
+			}
+			cmt.Text = string(p.data[p.off : p.off+end+4])
+			p.advance(end + 4)
+			cmts = append(cmts, cmt)
+		default:
+			return string(p.data[start:p.off]), cmts, nil
+		}
+	}
+	return string(p.data[start:p.off]), cmts, nil
+}
+
+func (p *astParser) peek(n int) byte {
+	if p.off+n < len(p.data) {
+		return p.data[p.off+n]
+	}
+	return 0
+}
+
+// splitTrailing split the comments on the given line from the others
+func splitTrailing(cmts []Comment, line int) (trailing, rest []Comment) {
+	for _, c := range cmts {
+		if c.Pos.Line == line {
+			trailing = append(trailing, c)
+		} else {
+			rest = append(rest, c)
+		}
+	}
+	return
+}
+
+func (p *astParser) value(before string, cmts []Comment) (*Node, error) {
+	if p.off >= len(p.data) {
+		return nil, p.errorf("unexpected end of input, want a json value")
+	}
+	n := &Node{Pos: p.pos(), before: before, Comments: cmts}
+	var err error
+	switch c := p.data[p.off]; {
+	case c == '{':
+		n.Kind = ObjectNode
+		err = p.object(n)
+	case c == '[':
+		n.Kind = ArrayNode
+		err = p.array(n)
+	case c == btDbQuote:
+		n.Kind = StringNode
+		n.Raw, err = p.str()
+	case c == '-' || (c >= '0' && c <= '9'):
+		n.Kind = NumberNode
+		err = p.number(n)
+	case c == 't' || c == 'f':
+		n.Kind = BoolNode
+		err = p.literal(n, "true", "false")
+	case c == 'n':
+		n.Kind = NullNode
+		err = p.literal(n, "null")
+	default:
+		err = p.errorf("unexpected %q, want a json value", c)
+	}
+	if err != nil {
+		return nil, err
+	}
+	n.End = p.pos()
+	return n, nil
+}
+
+func (p *astParser) object(n *Node) error {
+	p.advance(1) // {
+	var (
+		last    *Node
+		pending []Comment // comments after the last value without a following ","
+	)
+	for {
+		tb, tc, err := p.trivia()
+		if err != nil {
+			return err
+		}
+		tc, pending = append(pending, tc...), nil
+		if last != nil {
+			var trailing []Comment
+			trailing, tc = splitTrailing(tc, last.End.Line)
+			last.TrailingComments = append(last.TrailingComments, trailing...)
+		}
+		if p.off >= len(p.data) {
+			return p.errorf("unexpected end of input, want a key or '}'")
+		}
+		if p.data[p.off] == '}' {
+			if len(n.Members) > 0 && n.Members[len(n.Members)-1].hasComma {
+				return p.errorf("unexpected '}' after ','")
+			}
+			n.endBefore, n.EndComments = tb, tc
+			p.advance(1)
+			return nil
+		}
+		if len(n.Members) > 0 && !n.Members[len(n.Members)-1].hasComma {
+			return p.errorf("unexpected %q, want ',' or '}'", p.data[p.off])
+		}
+		if p.data[p.off] != btDbQuote {
+			return p.errorf("unexpected %q, want a string key", p.data[p.off])
+		}
+
+		m := &Member{KeyPos: p.pos(), keyBefore: tb, Comments: tc}
+		if m.KeyRaw, err = p.str(); err != nil {
+			return err
+		}
+		json.Unmarshal([]byte(m.KeyRaw), &m.Key)
+
+		if m.colonBefore, tc, err = p.trivia(); err != nil {
+			return err
+		}
+		m.Comments = append(m.Comments, tc...)
+		if p.off >= len(p.data) || p.data[p.off] != ':' {
+			return p.errorf("want ':' after the key %s", m.KeyRaw)
+		}
+		p.advance(1)
+
+		vb, vc, err := p.trivia()
+		if err != nil {
+			return err
+		}
+		if m.Value, err = p.value(vb, vc); err != nil {
+			return err
+		}
+		if m.commaBefore, tc, err = p.trivia(); err != nil {
+			return err
+		}
+		if p.off < len(p.data) && p.data[p.off] == ',' {
+			m.hasComma = true
+			p.advance(1)
+		}
+		// the comments on the other lines belong to the next member or the end
+		m.Value.TrailingComments, pending = splitTrailing(tc, m.Value.End.Line)
+		n.Members = append(n.Members, m)
+		last = m.Value
+	}
+}
+
+func (p *astParser) array(n *Node) error {
+	p.advance(1) // [
+	var (
+		last    *Node
+		pending []Comment // comments after the last value without a following ","
+	)
+	for {
+		tb, tc, err := p.trivia()
+		if err != nil {
+			return err
+		}
+		tc, pending = append(pending, tc...), nil
+		if last != nil {
+			var trailing []Comment
+			trailing, tc = splitTrailing(tc, last.End.Line)
+			last.TrailingComments = append(last.TrailingComments, trailing...)
+		}
+		if p.off >= len(p.data) {
+			return p.errorf("unexpected end of input, want a value or ']'")
+		}
+		if p.data[p.off] == ']' {
+			if last != nil && last.hasComma {
+				return p.errorf("unexpected ']' after ','")
+			}
+			n.endBefore, n.EndComments = tb, tc
+			p.advance(1)
+			return nil
+		}
+		if last != nil && !last.hasComma {
+			return p.errorf("unexpected %q, want ',' or ']'", p.data[p.off])
+		}
+
+		e, err := p.value(tb, tc)
+		if err != nil {
+			return err
+		}
+		if e.commaBefore, tc, err = p.trivia(); err != nil {
+			return err
+		}
+		if p.off < len(p.data) && p.data[p.off] == ',' {
+			e.hasComma = true
+			p.advance(1)
+		}
+		// the comments on the other lines belong to the next member or the end
+		e.TrailingComments, pending = splitTrailing(tc, e.End.Line)
+		n.Elems = append(n.Elems, e)
+		last = e
+	}
+}
+
+// str scan a json string, return the raw text include the quotes
+func (p *astParser) str() (string, error) {
+	start := p.pos()
+	i := p.off + 1
+	for ; i < len(p.data); i++ {
+		c := p.data[i]
+		if c == btBackslash {
+			i++
+			continue
+		}
+		if c == btDbQuote {
+			break
+		}
+		if c < 0x20 {
+			p.advance(i - p.off)
+			return "", p.errorf("invalid control character %q in string", c)
+		}
+	}
+	if i >= len(p.data) {
+		return "", newSyntaxError(p.data, start.Offset, "unterminated string")
+	}
+	raw := p.data[p.off : i+1]
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", p.errorf("invalid string %s: %s", raw, err)
+	}
+	p.advance(len(raw))
+	return string(raw), nil
+}
+
+func (p *astParser) number(n *Node) error {
+	i := p.off
+	for ; i < len(p.data); i++ {
+		c := p.data[i]
+		if !(c >= '0' && c <= '9') && c != '-' && c != '+' && c != '.' && c != 'e' && c != 'E' {
+			break
+		}
+	}
+	raw := p.data[p.off:i]
+	if !json.Valid(raw) {
+		return p.errorf("invalid number %s", raw)
+	}
+	n.Raw = string(raw)
+	p.advance(len(raw))
+	return nil
+}
+
+func (p *astParser) literal(n *Node, lits ...string) error {
+	for _, lit := range lits {
+		if bytes.HasPrefix(p.data[p.off:], []byte(lit)) {
+			n.Raw = lit
+			p.advance(len(lit))
+			return nil
+		}
+	}
+	return p.errorf("invalid literal, want %v", lits)
+}
@@ -0,0 +1,367 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iyidan/goutils/mise"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// BindError collect all the field errors of a conf.Bind call
+type BindError struct {
+	Errors []error
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "config.Bind: " + strings.Join(msgs, "; ")
+}
+
+// fieldTag is the parsed `config:"name,default=...,required"` struct tag
+type fieldTag struct {
+	name     string
+	def      string
+	hasDef   bool
+	required bool
+	skip     bool
+	squash   bool
+}
+
+func parseFieldTag(f reflect.StructField) fieldTag {
+	ft := fieldTag{name: f.Name}
+	tag, ok := f.Tag.Lookup("config")
+	if !ok {
+		// embedded structs without tag are squashed into the parent
+		ft.squash = f.Anonymous && indirectType(f.Type).Kind() == reflect.Struct
+		return ft
+	}
+	if tag == "-" {
+		ft.skip = true
+		return ft
+	}
+	parts := strings.Split(tag, ",")
+	if parts[0] != "" {
+		ft.name = parts[0]
+	}
+	for i := 1; i < len(parts); i++ {
+		switch opt := parts[i]; {
+		case opt == "required":
+			ft.required = true
+		case strings.HasPrefix(opt, "default="):
+			// the default value may contains ",", e.g. "default=a,b,c"
+			ft.def, ft.hasDef = strings.Join(append([]string{opt[len("default="):]}, parts[i+1:]...), ","), true
+			i = len(parts)
+		}
+	}
+	return ft
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// Bind bind config k into the struct pointed by v, k can be a dotted path,
+// an empty k bind the whole config. the struct fields use tags like:
+//
+//	type DBConf struct {
+//		Host    string        `config:"host,required"`
+//		Port    int           `config:"port,default=3306"`
+//		Timeout time.Duration `config:"timeout,default=3s"`
+//		Tags    []string      `config:"tags,default=a,b"`
+//		Ignored string        `config:"-"`
+//	}
+//
+// field names without tag are matched case-insensitively, values are coerced
+// the same way as the typed getters, e.g. "on" for bool, "30" for int,
+// "1m30s" for time.Duration and "2017-07-15 09:00:00" or RFC3339 for time.Time.
+// a slice default is split by ",". all field errors are reported together in a *BindError.
+// the encrypted values are decrypted by the key provider
+func (conf *Config) Bind(k string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config.Bind(%s): need a non-nil struct pointer, got %T", k, v)
+	}
+	var raw interface{}
+	if k == "" {
+		conf.cLock.RLock()
+		raw = conf.origin
+		conf.cLock.RUnlock()
+	} else {
+		var err error
+		if raw, err = conf.GetE(k); err != nil {
+			return err
+		}
+	}
+
+	plain, err := conf.decryptTree(k, raw)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	bindStruct(k, plain, rv.Elem(), &errs)
+	if len(errs) > 0 {
+		redactErrors(k, raw, errs)
+		return &BindError{Errors: errs}
+	}
+	return nil
+}
+
+// MustBind same as conf.Bind, if error, panic
+func (conf *Config) MustBind(k string, v interface{}) {
+	mise.PanicOnError(conf.Bind(k, v), "config")
+}
+
+func bindStruct(path string, raw interface{}, rv reflect.Value, errs *[]error) {
+	m, ok := raw.(map[string]interface{})
+	if !ok {
+		*errs = append(*errs, &TypeError{Key: path, Want: "map[string]interface{}", Value: raw})
+		return
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		f := rt.Field(i)
+		ft := parseFieldTag(f)
+		if ft.skip || (f.PkgPath != "" && !ft.squash) {
+			continue
+		}
+		fv := rv.Field(i)
+		if ft.squash {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					if !fv.CanSet() {
+						continue
+					}
+					fv.Set(reflect.New(f.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			bindStruct(path, m, fv, errs)
+			continue
+		}
+
+		fpath := joinPath(path, ft.name)
+		fraw, exists := m[ft.name]
+		if !exists {
+			for mk, mv := range m {
+				if strings.EqualFold(mk, ft.name) {
+					fraw, exists = mv, true
+					fpath = joinPath(path, mk)
+					break
+				}
+			}
+		}
+		if !exists {
+			switch {
+			case ft.hasDef:
+				fraw = defaultValue(ft.def, f.Type)
+			case ft.required:
+				*errs = append(*errs, &KeyError{Key: fpath})
+				continue
+			default:
+				continue
+			}
+		}
+		bindValue(fpath, fraw, fv, errs)
+	}
+}
+
+// defaultValue convert the tag default into a raw config value
+func defaultValue(def string, t reflect.Type) interface{} {
+	if k := indirectType(t).Kind(); k == reflect.Slice || k == reflect.Array {
+		if def == "" {
+			return []interface{}{}
+		}
+		parts := strings.Split(def, ",")
+		raw := make([]interface{}, len(parts))
+		for i := range parts {
+			raw[i] = strings.TrimSpace(parts[i])
+		}
+		return raw
+	}
+	return def
+}
+
+func bindValue(path string, raw interface{}, rv reflect.Value, errs *[]error) {
+	switch rv.Type() {
+	case durationType:
+		s, ok := raw.(string)
+		if !ok {
+			*errs = append(*errs, &TypeError{Key: path, Want: "time-duration-string", Value: raw})
+			return
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			*errs = append(*errs, &ParseError{Key: path, Want: "time-duration-string", Value: raw, Err: err})
+			return
+		}
+		rv.SetInt(int64(d))
+		return
+	case timeType:
+		s, ok := raw.(string)
+		if !ok {
+			*errs = append(*errs, &TypeError{Key: path, Want: "time-string", Value: raw})
+			return
+		}
+		tm, err := mise.StrToLocalTime(s)
+		if err != nil {
+			// the toml datetimes are normalized into RFC3339 strings
+			if rtm, rerr := time.Parse(time.RFC3339Nano, s); rerr == nil {
+				rv.Set(reflect.ValueOf(rtm))
+				return
+			}
+			*errs = append(*errs, &ParseError{Key: path, Want: "time-string", Value: raw, Err: err})
+			return
+		}
+		rv.Set(reflect.ValueOf(tm))
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if raw == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return
+		}
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		bindValue(path, raw, rv.Elem(), errs)
+	case reflect.Interface:
+		if raw == nil {
+			rv.Set(reflect.Zero(rv.Type()))
+			return
+		}
+		if !reflect.TypeOf(raw).AssignableTo(rv.Type()) {
+			*errs = append(*errs, &TypeError{Key: path, Want: rv.Type().String(), Value: raw})
+			return
+		}
+		rv.Set(reflect.ValueOf(raw))
+	case reflect.Struct:
+		bindStruct(path, raw, rv, errs)
+	case reflect.String:
+		s, ok := raw.(string)
+		if !ok {
+			*errs = append(*errs, &TypeError{Key: path, Want: "string", Value: raw})
+			return
+		}
+		rv.SetString(s)
+	case reflect.Bool:
+		b, err := mise.ParseBool(raw)
+		if err != nil {
+			*errs = append(*errs, &ParseError{Key: path, Want: "bool", Value: raw, Err: err})
+			return
+		}
+		rv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := mise.ParseInt64(raw)
+		if err == nil && rv.OverflowInt(n) {
+			err = fmt.Errorf("%d overflows %s", n, rv.Type())
+		}
+		if err != nil {
+			*errs = append(*errs, &ParseError{Key: path, Want: rv.Type().String(), Value: raw, Err: err})
+			return
+		}
+		rv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := parseUint64(raw)
+		if err == nil && rv.OverflowUint(n) {
+			err = fmt.Errorf("%d overflows %s", n, rv.Type())
+		}
+		if err != nil {
+			*errs = append(*errs, &ParseError{Key: path, Want: rv.Type().String(), Value: raw, Err: err})
+			return
+		}
+		rv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := mise.ParseFloat(raw)
+		if err == nil && rv.OverflowFloat(f) {
+			err = fmt.Errorf("%v overflows %s", f, rv.Type())
+		}
+		if err != nil {
+			*errs = append(*errs, &ParseError{Key: path, Want: rv.Type().String(), Value: raw, Err: err})
+			return
+		}
+		rv.SetFloat(f)
+	case reflect.Slice:
+		sv, ok := raw.([]interface{})
+		if !ok {
+			*errs = append(*errs, &TypeError{Key: path, Want: "[]interface{}", Value: raw})
+			return
+		}
+		nv := reflect.MakeSlice(rv.Type(), len(sv), len(sv))
+		for i := range sv {
+			bindValue(fmt.Sprintf("%s[%d]", path, i), sv[i], nv.Index(i), errs)
+		}
+		rv.Set(nv)
+	case reflect.Array:
+		sv, ok := raw.([]interface{})
+		if !ok || len(sv) != rv.Len() {
+			*errs = append(*errs, &TypeError{Key: path, Want: rv.Type().String(), Value: raw})
+			return
+		}
+		for i := range sv {
+			bindValue(fmt.Sprintf("%s[%d]", path, i), sv[i], rv.Index(i), errs)
+		}
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			*errs = append(*errs, fmt.Errorf("config.Bind: %s unsupported field type %s", path, rv.Type()))
+			return
+		}
+		mv, ok := raw.(map[string]interface{})
+		if !ok {
+			*errs = append(*errs, &TypeError{Key: path, Want: "map[string]interface{}", Value: raw})
+			return
+		}
+		nm := reflect.MakeMapWithSize(rv.Type(), len(mv))
+		for mk, mval := range mv {
+			ev := reflect.New(rv.Type().Elem()).Elem()
+			bindValue(joinPath(path, mk), mval, ev, errs)
+			nm.SetMapIndex(reflect.ValueOf(mk).Convert(rv.Type().Key()), ev)
+		}
+		rv.Set(nm)
+	default:
+		*errs = append(*errs, fmt.Errorf("config.Bind: %s unsupported field type %s", path, rv.Type()))
+	}
+}
+
+// parseUint64 parse any uint-like-value into uint64, mise.ParseInt64 can not hold the values above math.MaxInt64
+func parseUint64(raw interface{}) (uint64, error) {
+	v, kd := mise.GetValueKind(raw)
+	switch kd {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	case reflect.Float64, reflect.Float32:
+		f := v.Float()
+		if f != math.Trunc(f) {
+			return 0, fmt.Errorf("%#v parse uint-like-value failed, number has the decimal part", raw)
+		}
+		// 1<<64 is the first float64 above math.MaxUint64
+		if f < 0 || f >= 1<<64 {
+			return 0, fmt.Errorf("%v overflows uint64", f)
+		}
+		return uint64(f), nil
+	case reflect.String:
+		return strconv.ParseUint(v.String(), 10, 64)
+	}
+	n, err := mise.ParseInt64(raw)
+	if err == nil && n < 0 {
+		err = fmt.Errorf("%d overflows uint64", n)
+	}
+	return uint64(n), err
+}
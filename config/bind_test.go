@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testBindBase struct {
+	Name string `config:"name"`
+}
+
+type testBindConf struct {
+	testBindBase
+	Debug    bool              `config:"debug"`
+	Port     uint16            `config:"port,default=8080"`
+	Timeout  time.Duration     `config:"timeout"`
+	Start    time.Time         `config:"start"`
+	Ratio    float32           `config:"ratio"`
+	Tags     []string          `config:"tags,default=a,b"`
+	Weights  map[string]int    `config:"weights"`
+	DB       *testBindDBConf   `config:"db"`
+	Servers  []testBindDBConf  `config:"servers"`
+	Extra    interface{}       `config:"extra"`
+	Labels   map[string]string `config:"labels"`
+	MaxConns int
+	Ignored  string `config:"-"`
+	ignored  string
+}
+
+type testBindDBConf struct {
+	Host string `config:"host,required"`
+	Pool int    `config:"pool,default=10"`
+}
+
+func TestConfigBind(t *testing.T) {
+	conf, err := ParseFromData([]byte(`{
+		"app": {
+			"name": "test",
+			"debug": "on",
+			"timeout": "1m30s",
+			"start": "2017-07-15 09:00:00",
+			"ratio": "0.5",
+			"weights": {"a": "1", "b": 2},
+			"db": {"host": "localhost", "pool": "20"},
+			"servers": [{"host": "10.0.0.1"}, {"host": "10.0.0.2", "pool": 5}],
+			"extra": [1, "x"],
+			"maxconns": "100",
+			"Ignored": "x"
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := testBindConf{}
+	if err = conf.Bind("app", &c); err != nil {
+		t.Fatal(err)
+	}
+	start, _ := time.ParseInLocation("2006-01-02 15:04:05", "2017-07-15 09:00:00", time.Local)
+	want := testBindConf{
+		testBindBase: testBindBase{Name: "test"},
+		Debug:        true,
+		Port:         8080,
+		Timeout:      90 * time.Second,
+		Ratio:        0.5,
+		Tags:         []string{"a", "b"},
+		Weights:      map[string]int{"a": 1, "b": 2},
+		DB:           &testBindDBConf{Host: "localhost", Pool: 20},
+		Servers:      []testBindDBConf{{Host: "10.0.0.1", Pool: 10}, {Host: "10.0.0.2", Pool: 5}},
+		Extra:        []interface{}{float64(1), "x"},
+		MaxConns:     100,
+	}
+	if !c.Start.Equal(start) {
+		t.Fatalf("Start: %s != %s", c.Start, start)
+	}
+	c.Start = time.Time{}
+	if !reflect.DeepEqual(c, want) {
+		t.Fatalf("conf.Bind(app):\n%#v\nwant:\n%#v", c, want)
+	}
+
+	// bind the root
+	root := struct {
+		App struct {
+			Name string `config:"name"`
+		} `config:"app"`
+	}{}
+	if err = conf.Bind("", &root); err != nil {
+		t.Fatal(err)
+	}
+	if root.App.Name != "test" {
+		t.Fatalf("conf.Bind(root): %#v", root)
+	}
+}
+
+func TestConfigBindErrors(t *testing.T) {
+	conf, err := ParseFromData([]byte(`{
+		"debug": "maybe",
+		"port": 70000,
+		"timeout": "1x",
+		"servers": [{"pool": 1}],
+		"db": {}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := testBindConf{}
+	err = conf.Bind("", &c)
+	var berr *BindError
+	if !errors.As(err, &berr) {
+		t.Fatalf("conf.Bind should return *BindError, got: %v", err)
+	}
+	keys := map[string]bool{}
+	for _, ferr := range berr.Errors {
+		switch e := ferr.(type) {
+		case *KeyError:
+			keys[e.Key] = true
+		case *ParseError:
+			keys[e.Key] = true
+		case *TypeError:
+			keys[e.Key] = true
+		}
+	}
+	for _, k := range []string{"debug", "port", "timeout", "servers[0].host", "db.host"} {
+		if !keys[k] {
+			t.Fatalf("error of %s not reported: %v", k, err)
+		}
+	}
+	if len(berr.Errors) != 5 {
+		t.Fatalf("want 5 errors, got: %v", err)
+	}
+
+	if err = conf.Bind("", c); err == nil {
+		t.Fatal("bind to a non-pointer should fail")
+	}
+	if err = conf.Bind("not.exists", &c); !IsNotExist(err) {
+		t.Fatalf("bind a not exists key should return *KeyError, got: %v", err)
+	}
+}
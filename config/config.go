@@ -4,493 +4,649 @@ import (
 	"encoding/json"
 
 	"fmt"
-
+	"reflect"
 	"sync"
+	"sync/atomic"
 
 	"time"
 
-	"github.com/iyidan/goutils/cmtjson"
 	"github.com/iyidan/goutils/mise"
 )
 
-const (
-	cKeyInt = iota
-	cKeyInt64
-	cKeyFloat
-	cKeyBool
-	cKeySliceString
-	cKeySliceInt
-	cKeySliceFloat
-	cKeySliceBool
-	cKeyMapString
-	cKeyMapInt
-	cKeyMapFloat
-	cKeyMapBool
-	cKeyMapSliceString
-	cKeyMapSliceInt
-	cKeyMapSliceFloat
-	cKeyMapSliceBool
-)
-
 // Config store the json.Unmarshal data
 type Config struct {
 	origin map[string]interface{}
-	cached map[int]map[string]interface{}
+	cached atomic.Pointer[cache] // swapped on every fill and reload, read without locks
 	cLock  sync.RWMutex
+	ver    uint64 // increased on every reload, guarded by cLock
+
+	// sources record which layer each path came from, guarded by cLock
+	sources map[string]string
+
+	// resolved is true if the origin data is not the content of one file: changed by
+	// "@include" or "${...}", or merged from layers, guarded by cLock
+	resolved bool
+	// files are the config files of the last load, watched by conf.Watch, guarded by cLock
+	files []string
+
+	// load re-read the origin data and sources, used by conf.Reload
+	load func(info *loadInfo) (map[string]interface{}, map[string]string, error)
+	// rLock serialize the reloads, so an older load never replace a newer one
+	rLock sync.Mutex
+
+	subs  []subscriber
+	sLock sync.Mutex
+
+	// keys decrypt the "ENC[AES256_GCM,...]" values
+	keys KeyProvider
 }
 
 func newConf() *Config {
-	return &Config{
-		origin: make(map[string]interface{}),
-		cached: map[int]map[string]interface{}{
-			cKeyInt:            make(map[string]interface{}),
-			cKeyInt64:          make(map[string]interface{}),
-			cKeyFloat:          make(map[string]interface{}),
-			cKeyBool:           make(map[string]interface{}),
-			cKeySliceString:    make(map[string]interface{}),
-			cKeySliceInt:       make(map[string]interface{}),
-			cKeySliceFloat:     make(map[string]interface{}),
-			cKeySliceBool:      make(map[string]interface{}),
-			cKeyMapString:      make(map[string]interface{}),
-			cKeyMapInt:         make(map[string]interface{}),
-			cKeyMapFloat:       make(map[string]interface{}),
-			cKeyMapBool:        make(map[string]interface{}),
-			cKeyMapSliceString: make(map[string]interface{}),
-			cKeyMapSliceInt:    make(map[string]interface{}),
-			cKeyMapSliceFloat:  make(map[string]interface{}),
-			cKeyMapSliceBool:   make(map[string]interface{}),
-		},
-	}
-}
-
-func (conf *Config) cacheGet(cKey int, k string) (interface{}, bool) {
-	conf.cLock.RLock()
-	v, ok := conf.cached[cKey][k]
-	conf.cLock.RUnlock()
+	conf := &Config{origin: make(map[string]interface{})}
+	conf.cached.Store(newCache(0))
+	return conf
+}
+
+// cache is an immutable snapshot of the decoded values of a config version,
+// the readers load it without locks, a fill store a modified copy of it
+type cache struct {
+	ver    uint64
+	values map[reflect.Type]map[string]interface{} // type -> path -> decoded value
+}
+
+func newCache(ver uint64) *cache {
+	return &cache{ver: ver, values: make(map[reflect.Type]map[string]interface{})}
+}
+
+// resetCache drop all cached values and increase the version, must hold cLock
+func (conf *Config) resetCache() {
+	conf.ver++
+	conf.cached.Store(newCache(conf.ver))
+}
+
+func (conf *Config) cacheGet(typ reflect.Type, k string) (interface{}, bool) {
+	v, ok := conf.cached.Load().values[typ][k]
 	return v, ok
 }
-func (conf *Config) cacheSet(cKey int, k string, v interface{}) {
-	conf.cLock.Lock()
-	conf.cached[cKey][k] = v
-	conf.cLock.Unlock()
+
+// cacheSet cache v only if the config not reloaded since ver was read,
+// only the map of typ is copied, the values are filled once per path, so the copies are rare
+func (conf *Config) cacheSet(typ reflect.Type, k string, v interface{}, ver uint64) {
+	for {
+		old := conf.cached.Load()
+		if old.ver != ver {
+			return
+		}
+		tm := make(map[string]interface{}, len(old.values[typ])+1)
+		for ok, ov := range old.values[typ] {
+			tm[ok] = ov
+		}
+		tm[k] = v
+		c := &cache{ver: ver, values: make(map[reflect.Type]map[string]interface{}, len(old.values)+1)}
+		for ot, om := range old.values {
+			c.values[ot] = om
+		}
+		c.values[typ] = tm
+		if conf.cached.CompareAndSwap(old, c) {
+			return
+		}
+	}
 }
 
-// ParseFromFile parse config from the given file
-func ParseFromFile(filename string) (*Config, error) {
+// ParseFromFile parse config from the given file, decoded by its extension
+// (.json, .jsonc, .yaml, .yml, .toml or a registered one) or the WithFormat option,
+// the "@include" directives and "${...}" references are resolved here
+func ParseFromFile(filename string, opts ...Option) (*Config, error) {
+	o := newOptions(opts)
 	conf := newConf()
-	err := cmtjson.ParseFromFile(filename, &conf.origin)
+	conf.keys = o.keys
+	conf.load = func(info *loadInfo) (map[string]interface{}, map[string]string, error) {
+		origin, err := parseFile(filename, o.format, nil, info)
+		if err == nil {
+			origin, err = o.process(origin, info)
+		}
+		return origin, map[string]string{"": filename}, err
+	}
+	info := &loadInfo{}
+	origin, sources, err := conf.load(info)
 	if err != nil {
 		return nil, err
 	}
+	conf.origin, conf.sources, conf.resolved, conf.files = origin, sources, info.resolved, info.files
 	return conf, nil
 }
 
-// ParseFromData parse config with the given data
-func ParseFromData(data []byte) (*Config, error) {
+// ParseFromData parse config with the given data, commented json unless the WithFormat option,
+// the "@include" directives are relative to the current directory
+func ParseFromData(data []byte, opts ...Option) (*Config, error) {
+	o := newOptions(opts)
+	format := o.format
+	if format == "" {
+		format = FormatJSON
+	}
 	conf := newConf()
-	err := cmtjson.ParseFromBytes(data, &conf.origin)
-	if err != nil {
+	conf.keys = o.keys
+	info := &loadInfo{}
+	var err error
+	if conf.origin, err = decode(format, data); err != nil {
 		return nil, err
 	}
+	if err = processIncludes(conf.origin, ".", nil, info); err != nil {
+		return nil, err
+	}
+	if conf.origin, err = o.process(conf.origin, info); err != nil {
+		return nil, err
+	}
+	conf.resolved = info.resolved
 	return conf, nil
 }
 
+// lookup find the value of k, k may be a top-level key or
+// a dotted path with slice indices, like "servers[2].host"
+func (conf *Config) lookup(k string) (interface{}, uint64, bool) {
+	conf.cLock.RLock()
+	origin, ver := conf.origin, conf.ver
+	conf.cLock.RUnlock()
+
+	if v, ok := origin[k]; ok {
+		return v, ver, true
+	}
+	v, ok := lookupPath(origin, k)
+	return v, ver, ok
+}
+
+// get same as conf.GetE, but also return the config version
+func (conf *Config) get(k string) (interface{}, uint64, error) {
+	v, ver, ok := conf.lookup(k)
+	if !ok {
+		return nil, ver, &KeyError{Key: k}
+	}
+	return v, ver, nil
+}
+
+// GetE get a config with original value
+// k can be a dotted path like "db.pool.max" or "servers[2].host"
+// if k not exists, return a *KeyError
+func (conf *Config) GetE(k string) (interface{}, error) {
+	v, _, err := conf.get(k)
+	return v, err
+}
+
 // Get get a config with original value
+// k can be a dotted path like "db.pool.max" or "servers[2].host"
 // if k not exists, return nil
 func (conf *Config) Get(k string) interface{} {
-	if v, ok := conf.origin[k]; ok {
+	if v, _, ok := conf.lookup(k); ok {
 		return v
 	}
 	return nil
 }
 
 // StrictGet get a config with original value
+// k can be a dotted path like "db.pool.max" or "servers[2].host"
 // if k not exists, panic
 func (conf *Config) StrictGet(k string) interface{} {
-	v, ok := conf.origin[k]
-	if !ok {
-		mise.PanicOnError(fmt.Errorf("config not exists:"+k), "config")
-	}
+	v, err := conf.GetE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
+// StringE get a config with k, if k not exists or not string type, return error.
+// an "ENC[AES256_GCM,...]" value is decrypted by the key provider
+func (conf *Config) StringE(k string) (string, error) {
+	return GetE[string](conf, k)
+}
+
 // String get a config with k, if k not exists or parse error, panic
 func (conf *Config) String(k string) string {
-	v := conf.StrictGet(k)
-	sv, ok := v.(string)
-	if !ok {
-		mise.PanicOnError(fmt.Errorf("config not string type: %s => %#v", k, v), "config")
+	v, err := conf.StringE(k)
+	mise.PanicOnError(err, "config")
+	return v
+}
+
+// StringOr same as conf.StringE method, but return def on error
+func (conf *Config) StringOr(k string, def string) string {
+	if v, err := conf.StringE(k); err == nil {
+		return v
 	}
-	return sv
+	return def
+}
+
+// GetTimeE same as conf.StringE method
+func (conf *Config) GetTimeE(k string) (time.Time, error) {
+	return GetE[time.Time](conf, k)
 }
 
 // GetTime same as conf.String method
 func (conf *Config) GetTime(k string) time.Time {
-	s := conf.String(k)
-	tm, err := mise.StrToLocalTime(s)
-	if err != nil {
-		err = mise.WrapErrorMsg(err, fmt.Sprintf("config not time-string type: %s => %#v", k, s))
-		mise.PanicOnError(err, "config.GetTime")
-	}
+	tm, err := conf.GetTimeE(k)
+	mise.PanicOnError(err, "config.GetTime")
 	return tm
 }
 
+// GetTimeOr same as conf.StringOr method
+func (conf *Config) GetTimeOr(k string, def time.Time) time.Time {
+	if tm, err := conf.GetTimeE(k); err == nil {
+		return tm
+	}
+	return def
+}
+
+// GetDurationE same as conf.StringE method
+func (conf *Config) GetDurationE(k string) (time.Duration, error) {
+	return GetE[time.Duration](conf, k)
+}
+
 // GetDuration same as conf.String method
 func (conf *Config) GetDuration(k string) time.Duration {
-	s := conf.String(k)
-	d, err := time.ParseDuration(s)
-	if err != nil {
-		err = mise.WrapErrorMsg(err, fmt.Sprintf("config not time-duration-string type: %s => %#v", k, s))
-		mise.PanicOnError(err, "config.GetDuration")
-	}
+	d, err := conf.GetDurationE(k)
+	mise.PanicOnError(err, "config.GetDuration")
 	return d
 }
 
+// GetDurationOr same as conf.StringOr method
+func (conf *Config) GetDurationOr(k string, def time.Duration) time.Duration {
+	if d, err := conf.GetDurationE(k); err == nil {
+		return d
+	}
+	return def
+}
+
+// IntE same as conf.StringE method
+func (conf *Config) IntE(k string) (int, error) {
+	return GetE[int](conf, k)
+}
+
 // Int same as conf.String method
 func (conf *Config) Int(k string) int {
-	if cachedv, ok := conf.cacheGet(cKeyInt, k); ok {
-		return cachedv.(int)
-	}
-	v := conf.StrictGet(k)
-	iv, err := mise.ParseInt(v)
-	if err != nil {
-		mise.PanicOnError(err, "config")
+	v, err := conf.IntE(k)
+	mise.PanicOnError(err, "config")
+	return v
+}
+
+// IntOr same as conf.StringOr method
+func (conf *Config) IntOr(k string, def int) int {
+	if v, err := conf.IntE(k); err == nil {
+		return v
 	}
-	conf.cacheSet(cKeyInt, k, iv)
-	return iv
+	return def
+}
+
+// Int64E same as conf.StringE method
+func (conf *Config) Int64E(k string) (int64, error) {
+	return GetE[int64](conf, k)
 }
 
 // Int64 same as conf.String method
 func (conf *Config) Int64(k string) int64 {
-	if cachedv, ok := conf.cacheGet(cKeyInt64, k); ok {
-		return cachedv.(int64)
-	}
-	v := conf.StrictGet(k)
-	iv, err := mise.ParseInt64(v)
-	if err != nil {
-		mise.PanicOnError(err, "config")
+	v, err := conf.Int64E(k)
+	mise.PanicOnError(err, "config")
+	return v
+}
+
+// Int64Or same as conf.StringOr method
+func (conf *Config) Int64Or(k string, def int64) int64 {
+	if v, err := conf.Int64E(k); err == nil {
+		return v
 	}
-	conf.cacheSet(cKeyInt64, k, iv)
-	return iv
+	return def
+}
+
+// FloatE same as conf.StringE method
+func (conf *Config) FloatE(k string) (float64, error) {
+	return GetE[float64](conf, k)
 }
 
 // Float same as conf.String method
 func (conf *Config) Float(k string) float64 {
-	if cachedv, ok := conf.cacheGet(cKeyFloat, k); ok {
-		return cachedv.(float64)
-	}
-	v := conf.StrictGet(k)
-	fv, err := mise.ParseFloat(v)
-	if err != nil {
-		mise.PanicOnError(err, "config")
+	v, err := conf.FloatE(k)
+	mise.PanicOnError(err, "config")
+	return v
+}
+
+// FloatOr same as conf.StringOr method
+func (conf *Config) FloatOr(k string, def float64) float64 {
+	if v, err := conf.FloatE(k); err == nil {
+		return v
 	}
-	conf.cacheSet(cKeyFloat, k, fv)
-	return fv
+	return def
+}
+
+// BoolE same as conf.StringE method
+func (conf *Config) BoolE(k string) (bool, error) {
+	return GetE[bool](conf, k)
 }
 
 // Bool same as conf.String method
 func (conf *Config) Bool(k string) bool {
-	if cachedv, ok := conf.cacheGet(cKeyBool, k); ok {
-		return cachedv.(bool)
-	}
-	v := conf.StrictGet(k)
-	bv, err := mise.ParseBool(v)
-	if err != nil {
-		mise.PanicOnError(err, "config")
+	v, err := conf.BoolE(k)
+	mise.PanicOnError(err, "config")
+	return v
+}
+
+// BoolOr same as conf.StringOr method
+func (conf *Config) BoolOr(k string, def bool) bool {
+	if v, err := conf.BoolE(k); err == nil {
+		return v
 	}
-	conf.cacheSet(cKeyBool, k, bv)
-	return bv
+	return def
 }
 
-func sliceVal(id string, val interface{}) []interface{} {
+func sliceVal(id string, val interface{}) ([]interface{}, error) {
 	tmp, ok := val.([]interface{})
 	if !ok {
-		mise.PanicOnError(fmt.Errorf("config.sliceVal not []interface type: %s => %#v", id, val), "config")
+		return nil, &TypeError{Key: id, Want: "[]interface{}", Value: val}
 	}
-	return tmp
-}
-func sliceStringVal(id string, val interface{}) []string {
-	tmp := sliceVal(id, val)
-	ssv := make([]string, len(tmp))
-	for i := range tmp {
-		s, ok := tmp[i].(string)
-		if !ok {
-			mise.PanicOnError(fmt.Errorf("config.sliceStringVal not []string type: %s => %#v", id, tmp), "config")
-		}
-		ssv[i] = s
-	}
-	return ssv
+	return tmp, nil
 }
 
-func sliceIntVal(id string, val interface{}) []int {
-	tmp := sliceVal(id, val)
-	ssv := make([]int, len(tmp))
-	for i := range tmp {
-		iv, err := mise.ParseInt(tmp[i])
-		if err != nil {
-			mise.PanicOnError(err, "config")
-		}
-		ssv[i] = iv
+// SliceE same as conf.StringE method
+func (conf *Config) SliceE(k string) ([]interface{}, error) {
+	v, err := conf.GetE(k)
+	if err != nil {
+		return nil, err
 	}
-	return ssv
+	return sliceVal(k, v)
 }
 
-func sliceFloatVal(id string, val interface{}) []float64 {
-	tmp := sliceVal(id, val)
-	ssv := make([]float64, len(tmp))
-	for i := range tmp {
-		iv, err := mise.ParseFloat(tmp[i])
-		if err != nil {
-			mise.PanicOnError(err, "config")
-		}
-		ssv[i] = iv
-	}
-	return ssv
+// Slice same as conf.String method
+func (conf *Config) Slice(k string) []interface{} {
+	v, err := conf.SliceE(k)
+	mise.PanicOnError(err, "config")
+	return v
 }
 
-func sliceBoolVal(id string, val interface{}) []bool {
-	tmp := sliceVal(id, val)
-	ssv := make([]bool, len(tmp))
-	for i := range tmp {
-		iv, err := mise.ParseBool(tmp[i])
-		if err != nil {
-			mise.PanicOnError(err, "config")
-		}
-		ssv[i] = iv
+// SliceOr same as conf.StringOr method
+func (conf *Config) SliceOr(k string, def []interface{}) []interface{} {
+	if v, err := conf.SliceE(k); err == nil {
+		return v
 	}
-	return ssv
+	return def
 }
 
-// Slice same as conf.String method
-func (conf *Config) Slice(k string) []interface{} {
-	return sliceVal(k, conf.StrictGet(k))
+// SliceStringE same as conf.StringE method
+func (conf *Config) SliceStringE(k string) ([]string, error) {
+	return GetE[[]string](conf, k)
 }
 
 // SliceString same as conf.String method
 func (conf *Config) SliceString(k string) []string {
-	if cachedv, ok := conf.cacheGet(cKeySliceString, k); ok {
-		return cachedv.([]string)
-	}
-	v := sliceStringVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeySliceString, k, v)
+	v, err := conf.SliceStringE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
-// SliceInt same as conf.String method
-func (conf *Config) SliceInt(k string) []int {
-	if cachedv, ok := conf.cacheGet(cKeySliceInt, k); ok {
-		return cachedv.([]int)
+// SliceStringOr same as conf.StringOr method
+func (conf *Config) SliceStringOr(k string, def []string) []string {
+	if v, err := conf.SliceStringE(k); err == nil {
+		return v
 	}
-	v := sliceIntVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeySliceInt, k, v)
-	return v
+	return def
 }
 
-// SliceFloat same as conf.String method
-func (conf *Config) SliceFloat(k string) []float64 {
-	if cachedv, ok := conf.cacheGet(cKeySliceFloat, k); ok {
-		return cachedv.([]float64)
-	}
-	v := sliceFloatVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeySliceFloat, k, v)
-	return v
+// SliceIntE same as conf.StringE method
+func (conf *Config) SliceIntE(k string) ([]int, error) {
+	return GetE[[]int](conf, k)
 }
 
-// SliceBool same as conf.String method
-func (conf *Config) SliceBool(k string) []bool {
-	if cachedv, ok := conf.cacheGet(cKeySliceBool, k); ok {
-		return cachedv.([]bool)
-	}
-	v := sliceBoolVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeySliceBool, k, v)
+// SliceInt same as conf.String method
+func (conf *Config) SliceInt(k string) []int {
+	v, err := conf.SliceIntE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
-func mapStringVal(id string, val interface{}) map[string]interface{} {
-	tmp, ok := val.(map[string]interface{})
-	if !ok {
-		mise.PanicOnError(fmt.Errorf("config not map[string]interface{} type: %s => %#v", id, val), "config")
+// SliceIntOr same as conf.StringOr method
+func (conf *Config) SliceIntOr(k string, def []int) []int {
+	if v, err := conf.SliceIntE(k); err == nil {
+		return v
 	}
-	return tmp
+	return def
 }
 
-func mapStringStringVal(id string, val interface{}) map[string]string {
-	tmp := mapStringVal(id, val)
-	r := make(map[string]string)
-	for tmpkey, tmpval := range tmp {
-		sv, ok := tmpval.(string)
-		if !ok {
-			mise.PanicOnError(fmt.Errorf("config not map[string]string type: %s => %#v", id, tmp), "config")
-		}
-		r[tmpkey] = sv
-	}
-	return r
+// SliceFloatE same as conf.StringE method
+func (conf *Config) SliceFloatE(k string) ([]float64, error) {
+	return GetE[[]float64](conf, k)
 }
 
-func mapStringIntVal(id string, val interface{}) map[string]int {
-	tmp := mapStringVal(id, val)
-	r := make(map[string]int)
-	for tmpkey, tmpval := range tmp {
-		iv, err := mise.ParseInt(tmpval)
-		if err != nil {
-			mise.PanicOnError(err, "config")
-		}
-		r[tmpkey] = iv
-	}
-	return r
+// SliceFloat same as conf.String method
+func (conf *Config) SliceFloat(k string) []float64 {
+	v, err := conf.SliceFloatE(k)
+	mise.PanicOnError(err, "config")
+	return v
 }
 
-func mapStringFloatVal(id string, val interface{}) map[string]float64 {
-	tmp := mapStringVal(id, val)
-	r := make(map[string]float64)
-	for tmpkey, tmpval := range tmp {
-		iv, err := mise.ParseFloat(tmpval)
-		if err != nil {
-			mise.PanicOnError(err, "config")
-		}
-		r[tmpkey] = iv
+// SliceFloatOr same as conf.StringOr method
+func (conf *Config) SliceFloatOr(k string, def []float64) []float64 {
+	if v, err := conf.SliceFloatE(k); err == nil {
+		return v
 	}
-	return r
+	return def
 }
 
-func mapStringBoolVal(id string, val interface{}) map[string]bool {
-	tmp := mapStringVal(id, val)
-	r := make(map[string]bool)
-	for tmpkey, tmpval := range tmp {
-		iv, err := mise.ParseBool(tmpval)
-		if err != nil {
-			mise.PanicOnError(err, "config")
-		}
-		r[tmpkey] = iv
-	}
-	return r
+// SliceBoolE same as conf.StringE method
+func (conf *Config) SliceBoolE(k string) ([]bool, error) {
+	return GetE[[]bool](conf, k)
 }
 
-func mapStringSliceStringVal(id string, val interface{}) map[string][]string {
-	tmp := mapStringVal(id, val)
-	r := make(map[string][]string)
-	for tmpkey, tmpval := range tmp {
-		r[tmpkey] = sliceStringVal(id+"."+tmpkey, tmpval)
-	}
-	return r
+// SliceBool same as conf.String method
+func (conf *Config) SliceBool(k string) []bool {
+	v, err := conf.SliceBoolE(k)
+	mise.PanicOnError(err, "config")
+	return v
 }
 
-func mapStringSliceIntVal(id string, val interface{}) map[string][]int {
-	tmp := mapStringVal(id, val)
-	r := make(map[string][]int)
-	for tmpkey, tmpval := range tmp {
-		r[tmpkey] = sliceIntVal(id+"."+tmpkey, tmpval)
+// SliceBoolOr same as conf.StringOr method
+func (conf *Config) SliceBoolOr(k string, def []bool) []bool {
+	if v, err := conf.SliceBoolE(k); err == nil {
+		return v
 	}
-	return r
+	return def
 }
 
-func mapStringSliceFloatVal(id string, val interface{}) map[string][]float64 {
-	tmp := mapStringVal(id, val)
-	r := make(map[string][]float64)
-	for tmpkey, tmpval := range tmp {
-		r[tmpkey] = sliceFloatVal(id+"."+tmpkey, tmpval)
+func mapStringVal(id string, val interface{}) (map[string]interface{}, error) {
+	tmp, ok := val.(map[string]interface{})
+	if !ok {
+		return nil, &TypeError{Key: id, Want: "map[string]interface{}", Value: val}
 	}
-	return r
+	return tmp, nil
 }
 
-func mapStringSliceBoolVal(id string, val interface{}) map[string][]bool {
-	tmp := mapStringVal(id, val)
-	r := make(map[string][]bool)
-	for tmpkey, tmpval := range tmp {
-		r[tmpkey] = sliceBoolVal(id+"."+tmpkey, tmpval)
+// MapStringE same as conf.StringE method
+func (conf *Config) MapStringE(k string) (map[string]interface{}, error) {
+	v, err := conf.GetE(k)
+	if err != nil {
+		return nil, err
 	}
-	return r
+	return mapStringVal(k, v)
 }
 
 // MapString same as conf.String method
 func (conf *Config) MapString(k string) map[string]interface{} {
-	return mapStringVal(k, conf.StrictGet(k))
+	v, err := conf.MapStringE(k)
+	mise.PanicOnError(err, "config")
+	return v
+}
+
+// MapStringOr same as conf.StringOr method
+func (conf *Config) MapStringOr(k string, def map[string]interface{}) map[string]interface{} {
+	if v, err := conf.MapStringE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// MapStringStringE same as conf.StringE method
+func (conf *Config) MapStringStringE(k string) (map[string]string, error) {
+	return GetE[map[string]string](conf, k)
 }
 
 // MapStringString same as conf.String method
 func (conf *Config) MapStringString(k string) map[string]string {
-	if cachedv, ok := conf.cacheGet(cKeyMapString, k); ok {
-		return cachedv.(map[string]string)
-	}
-	v := mapStringStringVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeyMapString, k, v)
+	v, err := conf.MapStringStringE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
+// MapStringStringOr same as conf.StringOr method
+func (conf *Config) MapStringStringOr(k string, def map[string]string) map[string]string {
+	if v, err := conf.MapStringStringE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// MapStringIntE same as conf.StringE method
+func (conf *Config) MapStringIntE(k string) (map[string]int, error) {
+	return GetE[map[string]int](conf, k)
+}
+
 // MapStringInt same as conf.String method
 func (conf *Config) MapStringInt(k string) map[string]int {
-	if cachedv, ok := conf.cacheGet(cKeyMapInt, k); ok {
-		return cachedv.(map[string]int)
-	}
-	v := mapStringIntVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeyMapInt, k, v)
+	v, err := conf.MapStringIntE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
+// MapStringIntOr same as conf.StringOr method
+func (conf *Config) MapStringIntOr(k string, def map[string]int) map[string]int {
+	if v, err := conf.MapStringIntE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// MapStringFloatE same as conf.StringE method
+func (conf *Config) MapStringFloatE(k string) (map[string]float64, error) {
+	return GetE[map[string]float64](conf, k)
+}
+
 // MapStringFloat same as conf.String method
 func (conf *Config) MapStringFloat(k string) map[string]float64 {
-	if cachedv, ok := conf.cacheGet(cKeyMapFloat, k); ok {
-		return cachedv.(map[string]float64)
-	}
-	v := mapStringFloatVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeyMapFloat, k, v)
+	v, err := conf.MapStringFloatE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
+// MapStringFloatOr same as conf.StringOr method
+func (conf *Config) MapStringFloatOr(k string, def map[string]float64) map[string]float64 {
+	if v, err := conf.MapStringFloatE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// MapStringBoolE same as conf.StringE method
+func (conf *Config) MapStringBoolE(k string) (map[string]bool, error) {
+	return GetE[map[string]bool](conf, k)
+}
+
 // MapStringBool same as conf.String method
 func (conf *Config) MapStringBool(k string) map[string]bool {
-	if cachedv, ok := conf.cacheGet(cKeyMapBool, k); ok {
-		return cachedv.(map[string]bool)
-	}
-	v := mapStringBoolVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeyMapBool, k, v)
+	v, err := conf.MapStringBoolE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
+// MapStringBoolOr same as conf.StringOr method
+func (conf *Config) MapStringBoolOr(k string, def map[string]bool) map[string]bool {
+	if v, err := conf.MapStringBoolE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// MapStringSliceStringE same as conf.StringE method
+func (conf *Config) MapStringSliceStringE(k string) (map[string][]string, error) {
+	return GetE[map[string][]string](conf, k)
+}
+
 // MapStringSliceString same as conf.String method
 func (conf *Config) MapStringSliceString(k string) map[string][]string {
-	if cachedv, ok := conf.cacheGet(cKeyMapSliceString, k); ok {
-		return cachedv.(map[string][]string)
-	}
-	v := mapStringSliceStringVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeyMapSliceString, k, v)
+	v, err := conf.MapStringSliceStringE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
+// MapStringSliceStringOr same as conf.StringOr method
+func (conf *Config) MapStringSliceStringOr(k string, def map[string][]string) map[string][]string {
+	if v, err := conf.MapStringSliceStringE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// MapStringSliceIntE same as conf.StringE method
+func (conf *Config) MapStringSliceIntE(k string) (map[string][]int, error) {
+	return GetE[map[string][]int](conf, k)
+}
+
 // MapStringSliceInt same as conf.String method
 func (conf *Config) MapStringSliceInt(k string) map[string][]int {
-	if cachedv, ok := conf.cacheGet(cKeyMapSliceInt, k); ok {
-		return cachedv.(map[string][]int)
-	}
-	v := mapStringSliceIntVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeyMapSliceInt, k, v)
+	v, err := conf.MapStringSliceIntE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
+// MapStringSliceIntOr same as conf.StringOr method
+func (conf *Config) MapStringSliceIntOr(k string, def map[string][]int) map[string][]int {
+	if v, err := conf.MapStringSliceIntE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// MapStringSliceFloatE same as conf.StringE method
+func (conf *Config) MapStringSliceFloatE(k string) (map[string][]float64, error) {
+	return GetE[map[string][]float64](conf, k)
+}
+
 // MapStringSliceFloat same as conf.String method
 func (conf *Config) MapStringSliceFloat(k string) map[string][]float64 {
-	if cachedv, ok := conf.cacheGet(cKeyMapSliceFloat, k); ok {
-		return cachedv.(map[string][]float64)
-	}
-	v := mapStringSliceFloatVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeyMapSliceFloat, k, v)
+	v, err := conf.MapStringSliceFloatE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
+// MapStringSliceFloatOr same as conf.StringOr method
+func (conf *Config) MapStringSliceFloatOr(k string, def map[string][]float64) map[string][]float64 {
+	if v, err := conf.MapStringSliceFloatE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// MapStringSliceBoolE same as conf.StringE method
+func (conf *Config) MapStringSliceBoolE(k string) (map[string][]bool, error) {
+	return GetE[map[string][]bool](conf, k)
+}
+
 // MapStringSliceBool same as conf.String method
 func (conf *Config) MapStringSliceBool(k string) map[string][]bool {
-	if cachedv, ok := conf.cacheGet(cKeyMapSliceBool, k); ok {
-		return cachedv.(map[string][]bool)
-	}
-	v := mapStringSliceBoolVal(k, conf.StrictGet(k))
-	conf.cacheSet(cKeyMapSliceBool, k, v)
+	v, err := conf.MapStringSliceBoolE(k)
+	mise.PanicOnError(err, "config")
 	return v
 }
 
-// Unmarshal config k into v
+// MapStringSliceBoolOr same as conf.StringOr method
+func (conf *Config) MapStringSliceBoolOr(k string, def map[string][]bool) map[string][]bool {
+	if v, err := conf.MapStringSliceBoolE(k); err == nil {
+		return v
+	}
+	return def
+}
+
+// Unmarshal config k into v, the encrypted values are decrypted
 func (conf *Config) Unmarshal(k string, v interface{}) error {
-	tmp := conf.MapString(k)
-	data, err := json.Marshal(tmp)
+	tmp, err := conf.MapStringE(k)
+	if err != nil {
+		return err
+	}
+	plain, err := conf.decryptTree(k, tmp)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(plain)
 	if err != nil {
 		return mise.WrapErrorMsg(err, fmt.Sprintf("config.Unmarshal(%s) => %#v", k, tmp))
 	}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -166,6 +170,138 @@ func TestConfigFuncs(t *testing.T) {
 	testFunc("3")
 }
 
+var testPathConfig = `{
+	// nested objects
+	"db": {"pool": {"max": 10, "idle": "2"}, "timeout": "1m30s"},
+	# slices of objects
+	"servers": [
+		{"host": "10.0.0.1", "ports": [80, 443]},
+		{"host": "10.0.0.2", "ports": [8080]},
+		{"host": "10.0.0.3", "tags": {"zone": "a"}}
+	],
+	"matrix": [[1, 2], [3, 4]],
+	"dotted.key": "top"
+}`
+
+func TestConfigPath(t *testing.T) {
+	conf, err := ParseFromData([]byte(testPathConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.Int("db.pool.max"); v != 10 {
+		t.Fatalf(`conf.Int("db.pool.max") = %d, want 10`, v)
+	}
+	if v := conf.Int("db.pool.idle"); v != 2 {
+		t.Fatalf(`conf.Int("db.pool.idle") = %d, want 2`, v)
+	}
+	if v := conf.GetDuration("db.timeout"); v != 90*time.Second {
+		t.Fatalf(`conf.GetDuration("db.timeout") = %s, want 1m30s`, v)
+	}
+	if v := conf.String("servers[2].host"); v != "10.0.0.3" {
+		t.Fatalf(`conf.String("servers[2].host") = %s, want 10.0.0.3`, v)
+	}
+	if v := conf.SliceInt("servers[0].ports"); !reflect.DeepEqual(v, []int{80, 443}) {
+		t.Fatalf(`conf.SliceInt("servers[0].ports") = %v`, v)
+	}
+	if v := conf.Int("servers[1].ports[0]"); v != 8080 {
+		t.Fatalf(`conf.Int("servers[1].ports[0]") = %d, want 8080`, v)
+	}
+	if v := conf.MapStringString("servers[2].tags"); !reflect.DeepEqual(v, map[string]string{"zone": "a"}) {
+		t.Fatalf(`conf.MapStringString("servers[2].tags") = %v`, v)
+	}
+	if v := conf.Int("matrix[1][0]"); v != 3 {
+		t.Fatalf(`conf.Int("matrix[1][0]") = %d, want 3`, v)
+	}
+	if v := conf.String("dotted.key"); v != "top" {
+		t.Fatalf(`conf.String("dotted.key") = %s, want top`, v)
+	}
+
+	dbConf := struct {
+		Timeout string `json:"timeout"`
+	}{}
+	conf.MustUnmarshal("db", &dbConf)
+	if dbConf.Timeout != "1m30s" {
+		t.Fatalf("conf.MustUnmarshal(db) = %#v", dbConf)
+	}
+
+	for _, k := range []string{"db.pool.min", "servers[3].host", "servers[0].host.x", "matrix[a]", "matrix[0", "db..pool"} {
+		if v := conf.Get(k); v != nil {
+			t.Fatalf("conf.Get(%q) = %#v, want nil", k, v)
+		}
+	}
+
+	defer func() {
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), "servers[5].host") {
+			t.Fatalf("panic message should contain the path, got: %v", r)
+		}
+	}()
+	conf.String("servers[5].host")
+}
+
+func TestConfigErrors(t *testing.T) {
+	conf, err := ParseFromData([]byte(testConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = conf.IntE("NotExistsKey")
+	var kerr *KeyError
+	if !errors.As(err, &kerr) || kerr.Key != "NotExistsKey" || !IsNotExist(err) {
+		t.Fatalf("conf.IntE(NotExistsKey) should return *KeyError, got: %#v", err)
+	}
+
+	_, err = conf.StringE("IntKey")
+	var terr *TypeError
+	if !errors.As(err, &terr) || terr.Key != "IntKey" || IsNotExist(err) {
+		t.Fatalf("conf.StringE(IntKey) should return *TypeError, got: %#v", err)
+	}
+
+	_, err = conf.IntE("StringKey")
+	var perr *ParseError
+	if !errors.As(err, &perr) || perr.Key != "StringKey" || perr.Err == nil {
+		t.Fatalf("conf.IntE(StringKey) should return *ParseError, got: %#v", err)
+	}
+
+	_, err = conf.SliceIntE("SliceStringKey")
+	if !errors.As(err, &perr) || perr.Key != "SliceStringKey[0]" {
+		t.Fatalf("conf.SliceIntE(SliceStringKey) should return *ParseError, got: %#v", err)
+	}
+
+	_, err = conf.MapStringSliceBoolE("MapStringSliceKey")
+	if !errors.As(err, &perr) || perr.Key != "MapStringSliceKey.a[0]" {
+		t.Fatalf("conf.MapStringSliceBoolE(MapStringSliceKey) should return *ParseError, got: %#v", err)
+	}
+
+	_, err = conf.GetDurationE("StringKey")
+	if !errors.As(err, &perr) {
+		t.Fatalf("conf.GetDurationE(StringKey) should return *ParseError, got: %#v", err)
+	}
+
+	if v, err := conf.IntE("IntKey2"); err != nil || v != 32 {
+		t.Fatalf("conf.IntE(IntKey2) = %d, %v", v, err)
+	}
+
+	if v := conf.IntOr("NotExistsKey", 7); v != 7 {
+		t.Fatalf("conf.IntOr(NotExistsKey, 7) = %d", v)
+	}
+	if v := conf.IntOr("IntKey", 7); v != 32 {
+		t.Fatalf("conf.IntOr(IntKey, 7) = %d", v)
+	}
+	if v := conf.BoolOr("StringKey", true); v != true {
+		t.Fatalf("conf.BoolOr(StringKey, true) = %v", v)
+	}
+	if v := conf.GetDurationOr("NotExistsKey", time.Second); v != time.Second {
+		t.Fatalf("conf.GetDurationOr(NotExistsKey, 1s) = %s", v)
+	}
+	if v := conf.SliceStringOr("NotExistsKey", []string{"x"}); !reflect.DeepEqual(v, []string{"x"}) {
+		t.Fatalf("conf.SliceStringOr(NotExistsKey) = %v", v)
+	}
+	if v := conf.MapStringIntOr("MapStringIntKey", nil); v["d"] != -3 {
+		t.Fatalf("conf.MapStringIntOr(MapStringIntKey) = %v", v)
+	}
+}
+
 func BenchmarkConfigGet(b *testing.B) {
 	b.StopTimer()
 	conf, err := ParseFromData([]byte(testConfig))
@@ -186,6 +322,86 @@ func BenchmarkMapGet(b *testing.B) {
 	}
 }
 
+// rwCache is the RWMutex guarded cache before the lock-free one, kept as the benchmark baseline
+type rwCache struct {
+	lock   sync.RWMutex
+	values map[reflect.Type]map[string]interface{}
+}
+
+func (c *rwCache) get(typ reflect.Type, k string) (interface{}, bool) {
+	c.lock.RLock()
+	v, ok := c.values[typ][k]
+	c.lock.RUnlock()
+	return v, ok
+}
+
+func BenchmarkConfigIntParallel(b *testing.B) {
+	conf, err := ParseFromData([]byte(testConfig))
+	if err != nil {
+		b.Fatal(err)
+	}
+	key := "IntKey"
+	conf.Int(key)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = conf.Int(key)
+		}
+	})
+}
+
+func BenchmarkRWMutexCacheParallel(b *testing.B) {
+	typ := reflect.TypeOf(0)
+	c := &rwCache{values: map[reflect.Type]map[string]interface{}{typ: {"IntKey": 1}}}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = c.get(typ, "IntKey")
+		}
+	})
+}
+
+func BenchmarkConfigCacheParallel(b *testing.B) {
+	typ := reflect.TypeOf(0)
+	conf := newConf()
+	conf.cacheSet(typ, "IntKey", 1, 0)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_, _ = conf.cacheGet(typ, "IntKey")
+		}
+	})
+}
+
+func TestConfigCacheConcurrent(t *testing.T) {
+	conf, err := ParseFromData([]byte(`{"n": 0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				if v := conf.Int("n"); v < 0 || v > 100 {
+					t.Errorf("conf.Int(n) = %d", v)
+					return
+				}
+			}
+		}()
+	}
+	for i := 1; i <= 100; i++ {
+		if err := conf.Set("n", float64(i)); err != nil {
+			t.Fatal(err)
+		}
+		if v := conf.Int("n"); v != i {
+			t.Fatalf("conf.Int(n) = %d after Set(n, %d)", v, i)
+		}
+	}
+	wg.Wait()
+}
+
 func getTempfileWithJSON(data []byte) (string, error) {
 	tmpfile, err := ioutil.TempFile("", "getTempfileWithJSON")
 	if err != nil {
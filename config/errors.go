@@ -0,0 +1,49 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+)
+
+// KeyError the config key not exists
+type KeyError struct {
+	Key string
+}
+
+func (e *KeyError) Error() string {
+	return "config not exists: " + e.Key
+}
+
+// TypeError the config value is not the wanted type
+type TypeError struct {
+	Key   string
+	Want  string
+	Value interface{}
+}
+
+func (e *TypeError) Error() string {
+	return fmt.Sprintf("config not %s type: %s => %#v", e.Want, e.Key, e.Value)
+}
+
+// ParseError the config value can not be parsed into the wanted type
+type ParseError struct {
+	Key   string
+	Want  string
+	Value interface{}
+	Err   error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("config parse %s failed: %s => %#v: %s", e.Want, e.Key, e.Value, e.Err)
+}
+
+// Unwrap return the underlying parse error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// IsNotExist report whether err means the config key not exists
+func IsNotExist(err error) bool {
+	var kerr *KeyError
+	return errors.As(err, &kerr)
+}
@@ -0,0 +1,193 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/iyidan/goutils/cmtjson"
+	"github.com/iyidan/goutils/mise"
+	"gopkg.in/yaml.v2"
+)
+
+// Decoder decode the data of a format into a config tree,
+// the tree is normalized into the types json.Unmarshal produce after decoded,
+// e.g. the ints become float64 and the time.Time become RFC3339 strings
+type Decoder func(data []byte) (map[string]interface{}, error)
+
+// the built-in formats
+const (
+	FormatJSON = "json" // commented json, the default format
+	FormatYAML = "yaml"
+	FormatTOML = "toml"
+)
+
+var (
+	decLock    sync.RWMutex
+	decoders   = make(map[string]Decoder) // format -> decoder
+	extensions = make(map[string]string)  // file extension -> format
+)
+
+func init() {
+	RegisterDecoder(FormatJSON, decodeJSON, ".json", ".jsonc")
+	RegisterDecoder(FormatYAML, decodeYAML, ".yaml", ".yml")
+	RegisterDecoder(FormatTOML, decodeTOML, ".toml")
+}
+
+// RegisterDecoder register the decoder of format and the file extensions (like ".yaml") of it,
+// a registered format or extension is replaced
+func RegisterDecoder(format string, dec Decoder, exts ...string) {
+	decLock.Lock()
+	defer decLock.Unlock()
+	decoders[format] = dec
+	for _, ext := range exts {
+		extensions[strings.ToLower(ext)] = format
+	}
+}
+
+// WithFormat set the format of the config data or file instead of by the file extension,
+// the included files are still decoded by their extensions
+func WithFormat(format string) Option {
+	return func(o *options) {
+		o.format = format
+	}
+}
+
+// formatOf return the format of filename by its extension, commented json if unknown
+func formatOf(filename string) string {
+	decLock.RLock()
+	defer decLock.RUnlock()
+	if format, ok := extensions[strings.ToLower(filepath.Ext(filename))]; ok {
+		return format
+	}
+	return FormatJSON
+}
+
+// decode decode data of format into a normalized config tree
+func decode(format string, data []byte) (map[string]interface{}, error) {
+	decLock.RLock()
+	dec, ok := decoders[format]
+	decLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("config: unknown format %q", format)
+	}
+	origin, err := dec(data)
+	if err != nil {
+		return nil, err
+	}
+	if origin == nil {
+		return make(map[string]interface{}), nil
+	}
+	v, err := normalizeTree(origin)
+	if err != nil {
+		return nil, mise.WrapErrorMsg(err, "config: "+format)
+	}
+	return v.(map[string]interface{}), nil
+}
+
+// decodeFile decode filename by format, by its extension if format is empty
+func decodeFile(filename string, format string) (map[string]interface{}, error) {
+	if format == "" {
+		format = formatOf(filename)
+	}
+	if format == FormatJSON {
+		// cmtjson report the syntax errors with the filename and decode large files while reading
+		origin := make(map[string]interface{})
+		if err := cmtjson.ParseFromFile(filename, &origin); err != nil {
+			return nil, err
+		}
+		return origin, nil
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	origin, err := decode(format, data)
+	if err != nil {
+		return nil, mise.WrapErrorMsg(err, "config: "+filename)
+	}
+	return origin, nil
+}
+
+func decodeJSON(data []byte) (map[string]interface{}, error) {
+	origin := make(map[string]interface{})
+	// cmtjson.ParseFromBytes modify the data in place
+	err := cmtjson.ParseFromBytes(append([]byte(nil), data...), &origin)
+	return origin, err
+}
+
+func decodeYAML(data []byte) (map[string]interface{}, error) {
+	origin := make(map[string]interface{})
+	err := yaml.Unmarshal(data, &origin)
+	return origin, err
+}
+
+func decodeTOML(data []byte) (map[string]interface{}, error) {
+	origin := make(map[string]interface{})
+	_, err := toml.Decode(string(data), &origin)
+	return origin, err
+}
+
+// normalizeTree convert the decoded v into the types json.Unmarshal produce,
+// the json objects are converted in place
+func normalizeTree(v interface{}) (interface{}, error) {
+	switch tv := v.(type) {
+	case nil, string, bool, float64:
+		return v, nil
+	case map[string]interface{}:
+		for k, mv := range tv {
+			nv, err := normalizeTree(mv)
+			if err != nil {
+				return nil, err
+			}
+			tv[k] = nv
+		}
+		return tv, nil
+	case map[interface{}]interface{}:
+		// yaml decode the nested mappings into it
+		m := make(map[string]interface{}, len(tv))
+		for k, mv := range tv {
+			nv, err := normalizeTree(mv)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprint(k)] = nv
+		}
+		return m, nil
+	case []interface{}:
+		for i := range tv {
+			nv, err := normalizeTree(tv[i])
+			if err != nil {
+				return nil, err
+			}
+			tv[i] = nv
+		}
+		return tv, nil
+	case []map[string]interface{}:
+		// toml decode the arrays of tables into it
+		s := make([]interface{}, len(tv))
+		for i := range tv {
+			nv, err := normalizeTree(tv[i])
+			if err != nil {
+				return nil, err
+			}
+			s[i] = nv
+		}
+		return s, nil
+	case int:
+		return float64(tv), nil
+	case int64:
+		return float64(tv), nil
+	case uint64:
+		return float64(tv), nil
+	case float32:
+		return float64(tv), nil
+	case time.Time:
+		return tv.Format(time.RFC3339Nano), nil
+	}
+	return normalize(v)
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfigFormats(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"app.jsonc": `{
+			// commented json
+			"name": "app",
+			"port": 8080,
+			"ratio": 0.5,
+			"tags": ["a", "b"],
+			"features": {"x": true, "z": false},
+			"servers": [{"host": "h1"}, {"host": "h2"}],
+			"created": "2019-01-02T03:04:05Z"
+		}`,
+		"app.yml": `# yaml
+name: app
+port: 8080
+ratio: 0.5
+tags: [a, b]
+features:
+  x: true
+  z: false
+servers:
+  - host: h1
+  - host: h2
+created: "2019-01-02T03:04:05Z"
+`,
+		"app.toml": `# toml
+name = "app"
+port = 8080
+ratio = 0.5
+tags = ["a", "b"]
+created = 2019-01-02T03:04:05Z
+
+[features]
+x = true
+z = false
+
+[[servers]]
+host = "h1"
+
+[[servers]]
+host = "h2"
+`,
+		"main.yaml": "'@include': base.json\nname: main\n",
+		"base.json": `{"name": "base", "region": "cn"}`,
+		"app.conf":  "name: app\n",
+	})
+	defer os.RemoveAll(dir)
+
+	var want map[string]interface{}
+	for _, name := range []string{"app.jsonc", "app.yml", "app.toml"} {
+		conf, err := ParseFromFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		if conf.Int("port") != 8080 || conf.Float("ratio") != 0.5 || conf.String("servers[1].host") != "h2" {
+			t.Fatalf("%s: wrong values %#v", name, conf.origin)
+		}
+		if !reflect.DeepEqual(conf.SliceString("tags"), []string{"a", "b"}) {
+			t.Fatalf("%s: SliceString = %v", name, conf.SliceString("tags"))
+		}
+		if !reflect.DeepEqual(conf.MapStringBool("features"), map[string]bool{"x": true, "z": false}) {
+			t.Fatalf("%s: MapStringBool = %v", name, conf.MapStringBool("features"))
+		}
+		if tm := conf.GetTime("created"); !tm.Equal(time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC)) {
+			t.Fatalf("%s: GetTime = %s", name, tm)
+		}
+		if want == nil {
+			want = conf.origin
+		} else if !reflect.DeepEqual(conf.origin, want) {
+			t.Fatalf("%s: got %#v\nwant %#v", name, conf.origin, want)
+		}
+	}
+
+	// a yaml file include a json file
+	conf, err := ParseFromFile(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.String("name") != "main" || conf.String("region") != "cn" {
+		t.Fatalf("include: %#v", conf.origin)
+	}
+
+	// the explicit format
+	if _, err = ParseFromFile(filepath.Join(dir, "app.conf")); err == nil {
+		t.Fatal("app.conf should be parsed as json")
+	}
+	if conf, err = ParseFromFile(filepath.Join(dir, "app.conf"), WithFormat(FormatYAML)); err != nil || conf.String("name") != "app" {
+		t.Fatalf("WithFormat: %v", err)
+	}
+	if conf, err = ParseFromData([]byte("port = 1\n"), WithFormat(FormatTOML)); err != nil || conf.Int("port") != 1 {
+		t.Fatalf("WithFormat: %v", err)
+	}
+	if _, err = ParseFromData([]byte("{}"), WithFormat("ini")); err == nil || !strings.Contains(err.Error(), "unknown format") {
+		t.Fatalf("want unknown format error, got %v", err)
+	}
+	// the errors name the file
+	dir2 := writeTestFiles(t, map[string]string{"bad.yaml": "a: [1\n"})
+	defer os.RemoveAll(dir2)
+	if _, err = ParseFromFile(filepath.Join(dir2, "bad.yaml")); err == nil || !strings.Contains(err.Error(), "bad.yaml") {
+		t.Fatalf("want error with filename, got %v", err)
+	}
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	// key=value lines
+	RegisterDecoder("kv", func(data []byte) (map[string]interface{}, error) {
+		m := make(map[string]interface{})
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			kv := strings.SplitN(line, "=", 2)
+			m[kv[0]] = map[interface{}]interface{}{"value": kv[1], "len": len(kv[1])}
+		}
+		return m, nil
+	}, ".kv")
+
+	conf, err := NewLoader().
+		AddData("base.json", []byte(`{"a": {"value": "json"}}`)).
+		AddData("override.kv", []byte("a=kv\nb=xyz")).
+		Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.String("a.value") != "kv" || conf.Int("b.len") != 3 {
+		t.Fatalf("got %#v", conf.origin)
+	}
+	if conf.Source("b.len") != "override.kv" {
+		t.Fatalf("Source = %q", conf.Source("b.len"))
+	}
+}
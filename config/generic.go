@@ -0,0 +1,57 @@
+package config
+
+import (
+	"reflect"
+
+	"github.com/iyidan/goutils/mise"
+)
+
+// GetE get the config of k decoded into T, T can be any combination of slices, arrays,
+// maps with string keys, structs (like conf.Bind), pointers, strings, bools, ints, uints,
+// floats, time.Duration and time.Time, the values are coerced by the mise.Parse* functions.
+// an "ENC[AES256_GCM,...]" value is decrypted by the key provider.
+// the result is cached by T and k until the config changes, it is shared by the callers, do not modify it
+//
+//	ports := config.Get[[]int](conf, "server.ports")
+//	timeouts := config.Get[map[string]time.Duration](conf, "timeouts")
+func GetE[T any](conf *Config, k string) (T, error) {
+	// a nil *T not escape to heap on the cached path
+	typ := reflect.TypeOf((*T)(nil)).Elem()
+	if cv, ok := conf.cacheGet(typ, k); ok {
+		return cv.(T), nil
+	}
+	var zero T
+	raw, ver, err := conf.get(k)
+	if err != nil {
+		return zero, err
+	}
+	plain, err := conf.decryptTree(k, raw)
+	if err != nil {
+		return zero, err
+	}
+	rv := reflect.New(typ)
+	var errs []error
+	bindValue(k, plain, rv.Elem(), &errs)
+	if len(errs) > 0 {
+		redactErrors(k, raw, errs)
+		return zero, errs[0]
+	}
+	v := *rv.Interface().(*T)
+	conf.cacheSet(typ, k, v, ver)
+	return v, nil
+}
+
+// Get same as GetE, if error, panic
+func Get[T any](conf *Config, k string) T {
+	v, err := GetE[T](conf, k)
+	mise.PanicOnError(err, "config")
+	return v
+}
+
+// GetOr same as GetE, but return def on error
+func GetOr[T any](conf *Config, k string, def T) T {
+	if v, err := GetE[T](conf, k); err == nil {
+		return v
+	}
+	return def
+}
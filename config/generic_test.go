@@ -0,0 +1,136 @@
+package config
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenericGet(t *testing.T) {
+	conf, err := ParseFromData([]byte(`{
+		"ports": [80, "443", 8080.0],
+		"timeouts": {"read": "1s", "write": "500ms"},
+		"servers": [{"host": "h1", "zone": "a"}, {"host": "h2"}],
+		"groups": {"admin": [1, 2], "guest": []},
+		"since": "2018-01-02 03:04:05",
+		"u8": 255,
+		"big": 256,
+		"flags": ["on", false, "0"],
+		"pair": [1, 2],
+		"none": null
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if v := Get[[]int64](conf, "ports"); !reflect.DeepEqual(v, []int64{80, 443, 8080}) {
+		t.Fatalf("Get[[]int64](ports) = %v", v)
+	}
+	if v := Get[map[string]time.Duration](conf, "timeouts"); !reflect.DeepEqual(v, map[string]time.Duration{"read": time.Second, "write": 500 * time.Millisecond}) {
+		t.Fatalf("Get[map[string]time.Duration](timeouts) = %v", v)
+	}
+	if v := Get[[]map[string]string](conf, "servers"); !reflect.DeepEqual(v, []map[string]string{{"host": "h1", "zone": "a"}, {"host": "h2"}}) {
+		t.Fatalf("Get[[]map[string]string](servers) = %v", v)
+	}
+	if v := Get[map[string][]uint](conf, "groups"); !reflect.DeepEqual(v, map[string][]uint{"admin": {1, 2}, "guest": {}}) {
+		t.Fatalf("Get[map[string][]uint](groups) = %v", v)
+	}
+	if v := Get[time.Time](conf, "since"); v.Year() != 2018 || v.Second() != 5 {
+		t.Fatalf("Get[time.Time](since) = %v", v)
+	}
+	if v := Get[uint8](conf, "u8"); v != 255 {
+		t.Fatalf("Get[uint8](u8) = %v", v)
+	}
+	if v := Get[[]bool](conf, "flags"); !reflect.DeepEqual(v, []bool{true, false, false}) {
+		t.Fatalf("Get[[]bool](flags) = %v", v)
+	}
+	if v := Get[[2]float32](conf, "pair"); v != [2]float32{1, 2} {
+		t.Fatalf("Get[[2]float32](pair) = %v", v)
+	}
+	if v := Get[interface{}](conf, "none"); v != nil {
+		t.Fatalf("Get[interface{}](none) = %v", v)
+	}
+	if v := Get[*int](conf, "u8"); v == nil || *v != 255 {
+		t.Fatalf("Get[*int](u8) = %v", v)
+	}
+
+	if _, err := GetE[uint8](conf, "big"); err == nil {
+		t.Fatal("want an overflow error")
+	} else if pe, ok := err.(*ParseError); !ok || pe.Key != "big" {
+		t.Fatalf("GetE[uint8](big) error = %#v", err)
+	}
+	// the uints above math.MaxInt64
+	uconf, err := ParseFromData([]byte(`{
+		"max": "18446744073709551615",
+		"maxnum": 18446744073709551615,
+		"bignum": 18446744073709549568,
+		"neg": -1,
+		"half": 1.5
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := GetE[uint64](uconf, "max"); err != nil || v != math.MaxUint64 {
+		t.Fatalf("GetE[uint64](max) = %v, %v", v, err)
+	}
+	if v, err := GetE[uint64](uconf, "bignum"); err != nil || v != 18446744073709549568 {
+		t.Fatalf("GetE[uint64](bignum) = %v, %v", v, err)
+	}
+	// 18446744073709551615 is rounded to 1<<64 as a float64
+	for k, msg := range map[string]string{"maxnum": "overflows", "neg": "overflows", "half": "decimal part"} {
+		if _, err := GetE[uint64](uconf, k); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("GetE[uint64](%s) want %q error, got %v", k, msg, err)
+		}
+	}
+	if _, err := GetE[[]int](conf, "servers"); err == nil {
+		t.Fatal("want a parse error")
+	} else if pe, ok := err.(*ParseError); !ok || pe.Key != "servers[0]" {
+		t.Fatalf("GetE[[]int](servers) error = %#v", err)
+	}
+	if _, err := GetE[string](conf, "nope"); err == nil {
+		t.Fatal("want a key error")
+	} else if _, ok := err.(*KeyError); !ok {
+		t.Fatalf("GetE[string](nope) error = %#v", err)
+	}
+	if v := GetOr[[]string](conf, "ports", []string{"def"}); !reflect.DeepEqual(v, []string{"def"}) {
+		t.Fatalf("GetOr[[]string](ports) = %v", v)
+	}
+
+	// the typed methods share the cache with GetE of the same type
+	Get[[]int](conf, "pair")
+	if _, ok := conf.cacheGet(reflect.TypeOf([]int(nil)), "pair"); !ok {
+		t.Fatal("GetE not cached")
+	}
+	if v := conf.SliceInt("pair"); !reflect.DeepEqual(v, []int{1, 2}) {
+		t.Fatalf("SliceInt(pair) = %v", v)
+	}
+	if err := conf.Set("pair", []interface{}{3.0}); err != nil {
+		t.Fatal(err)
+	}
+	if v := Get[[]int](conf, "pair"); !reflect.DeepEqual(v, []int{3}) {
+		t.Fatalf("Get[[]int](pair) after Set = %v", v)
+	}
+}
+
+func TestGenericGetSecret(t *testing.T) {
+	key, _ := GenerateKey()
+	raw, _ := DecodeKey(key)
+	enc, err := Encrypt(raw, "30s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf, err := ParseFromData([]byte(`{"timeout": "`+enc+`"}`), WithKeyProvider(KeyProviderFunc(func() ([]byte, error) {
+		return raw, nil
+	})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := Get[time.Duration](conf, "timeout"); v != 30*time.Second {
+		t.Fatalf("Get[time.Duration](timeout) = %v", v)
+	}
+	if v := conf.String("timeout"); v != "30s" {
+		t.Fatalf("String(timeout) = %v", v)
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ChangeKind is the kind of a Change
+type ChangeKind int
+
+// change kinds
+const (
+	Added ChangeKind = iota
+	Removed
+	Changed
+)
+
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	case Changed:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// Change is a difference of a path between two configs,
+// Old is nil if added, New is nil if removed
+type Change struct {
+	Path string
+	Kind ChangeKind
+	Old  interface{}
+	New  interface{}
+}
+
+// flatten collect the leaf values of v by path, the leaves are the
+// non-object values and the empty objects, slices are not walked into
+func flatten(path string, v interface{}, leaves map[string]interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok || (len(m) == 0 && path != "") {
+		leaves[path] = v
+		return
+	}
+	for k, mv := range m {
+		flatten(joinPath(path, k), mv, leaves)
+	}
+}
+
+func (conf *Config) snapshot() map[string]interface{} {
+	conf.cLock.RLock()
+	defer conf.cLock.RUnlock()
+	return conf.origin
+}
+
+// Keys return the sorted leaf paths under prefix, like "db.host",
+// slices are leaves. prefix is a path like "db", "" return all the keys
+func (conf *Config) Keys(prefix string) []string {
+	leaves := make(map[string]interface{})
+	flatten("", conf.snapshot(), leaves)
+	keys := make([]string, 0, len(leaves))
+	for k := range leaves {
+		if prefix == "" || k == prefix || strings.HasPrefix(k, prefix+".") || strings.HasPrefix(k, prefix+"[") {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Has report whether the path exists
+func (conf *Config) Has(path string) bool {
+	_, _, ok := conf.lookup(path)
+	return ok
+}
+
+// Sub return a config of the object at path, the paths of it are relative to path.
+// it is a snapshot which share the data with conf, the later Reload, Set or Delete
+// of conf not change it. return nil if path not exists or not an object
+func (conf *Config) Sub(path string) *Config {
+	v, _, ok := conf.lookup(path)
+	if !ok {
+		return nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	conf.cLock.RLock()
+	sources, resolved := conf.sources, conf.resolved
+	conf.cLock.RUnlock()
+
+	sub := newConf()
+	sub.origin, sub.keys, sub.resolved = m, conf.keys, resolved
+	if sources != nil {
+		sub.sources = make(map[string]string)
+		if name, ok := layerSource(sources, path); ok {
+			sub.sources[""] = name
+		}
+		for k, name := range sources {
+			if strings.HasPrefix(k, path+".") {
+				sub.sources[k[len(path)+1:]] = name
+			}
+		}
+	}
+	return sub
+}
+
+// AllSettings return a deep copy of the whole config
+func (conf *Config) AllSettings() map[string]interface{} {
+	return deepCopy(conf.snapshot()).(map[string]interface{})
+}
+
+func deepCopy(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		nm := make(map[string]interface{}, len(tv))
+		for k, mv := range tv {
+			nm[k] = deepCopy(mv)
+		}
+		return nm
+	case []interface{}:
+		ns := make([]interface{}, len(tv))
+		for i := range tv {
+			ns[i] = deepCopy(tv[i])
+		}
+		return ns
+	}
+	return v
+}
+
+// Diff report the changes of the leaf paths (see conf.Keys) from conf to other, sorted by path
+func (conf *Config) Diff(other *Config) []Change {
+	oldLeaves := make(map[string]interface{})
+	newLeaves := make(map[string]interface{})
+	flatten("", conf.snapshot(), oldLeaves)
+	flatten("", other.snapshot(), newLeaves)
+
+	var changes []Change
+	for k, ov := range oldLeaves {
+		nv, ok := newLeaves[k]
+		if !ok {
+			changes = append(changes, Change{Path: k, Kind: Removed, Old: ov})
+		} else if !reflect.DeepEqual(ov, nv) {
+			changes = append(changes, Change{Path: k, Kind: Changed, Old: ov, New: nv})
+		}
+	}
+	for k, nv := range newLeaves {
+		if _, ok := oldLeaves[k]; !ok {
+			changes = append(changes, Change{Path: k, Kind: Added, New: nv})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
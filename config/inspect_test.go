@@ -0,0 +1,79 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigInspect(t *testing.T) {
+	conf, err := ParseFromData([]byte(`{
+		"name": "app",
+		"db": {"host": "localhost", "pool": {"max": 10}, "opts": {}},
+		"dbx": 1,
+		"servers": [{"host": "h1"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if keys := conf.Keys(""); !reflect.DeepEqual(keys, []string{"db.host", "db.opts", "db.pool.max", "dbx", "name", "servers"}) {
+		t.Fatalf("Keys() = %v", keys)
+	}
+	if keys := conf.Keys("db"); !reflect.DeepEqual(keys, []string{"db.host", "db.opts", "db.pool.max"}) {
+		t.Fatalf("Keys(db) = %v", keys)
+	}
+	if !conf.Has("db.pool.max") || !conf.Has("servers[0].host") || conf.Has("db.user") {
+		t.Fatal("Has error")
+	}
+
+	db := conf.Sub("db")
+	if db == nil || db.Int("pool.max") != 10 || db.String("host") != "localhost" {
+		t.Fatalf("Sub(db) = %#v", db)
+	}
+	if conf.Sub("name") != nil || conf.Sub("nope") != nil {
+		t.Fatal("Sub of a non-object should be nil")
+	}
+
+	all := conf.AllSettings()
+	all["db"].(map[string]interface{})["host"] = "changed"
+	all["servers"].([]interface{})[0].(map[string]interface{})["host"] = "changed"
+	if conf.String("db.host") != "localhost" || conf.String("servers[0].host") != "h1" {
+		t.Fatal("AllSettings is not a deep copy")
+	}
+
+	other, err := ParseFromData([]byte(`{
+		"name": "app2",
+		"db": {"host": "localhost", "pool": {"max": 10, "min": 1}},
+		"servers": [{"host": "h1"}, {"host": "h2"}]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Change{
+		{Path: "db.opts", Kind: Removed, Old: map[string]interface{}{}},
+		{Path: "db.pool.min", Kind: Added, New: 1.0},
+		{Path: "dbx", Kind: Removed, Old: 1.0},
+		{Path: "name", Kind: Changed, Old: "app", New: "app2"},
+		{Path: "servers", Kind: Changed, Old: conf.Get("servers"), New: other.Get("servers")},
+	}
+	if changes := conf.Diff(other); !reflect.DeepEqual(changes, want) {
+		t.Fatalf("Diff = %#v", changes)
+	}
+	if changes := conf.Diff(conf); len(changes) != 0 {
+		t.Fatalf("Diff(self) = %#v", changes)
+	}
+}
+
+func TestConfigSubSource(t *testing.T) {
+	conf, err := NewLoader().
+		AddData("base", []byte(`{"db": {"host": "h", "port": 1}}`)).
+		AddData("override", []byte(`{"db": {"port": 2}}`)).
+		Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	db := conf.Sub("db")
+	if db.Source("host") != "base" || db.Source("port") != "override" {
+		t.Fatalf("Source = %q, %q", db.Source("host"), db.Source("port"))
+	}
+}
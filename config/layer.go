@@ -0,0 +1,310 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"strings"
+)
+
+// layer is one config source of a Loader
+type layer struct {
+	name     string
+	foldKeys bool // match keys case-insensitively against the lower layers
+	load     func(info *loadInfo) (map[string]interface{}, map[string]string, error)
+}
+
+// Loader merge several config sources into one Config,
+// later added layers override the earlier ones:
+//
+//	conf, err := config.NewLoader().
+//		AddFile("base.json").
+//		AddOptionalFile("prod.json").
+//		AddOptionalFile("local.json").
+//		AddEnv("APP_").
+//		AddFlags(flag.CommandLine).
+//		Load()
+//
+// objects are deep merged, other values (include slices) are replaced
+type Loader struct {
+	layers []layer
+}
+
+// NewLoader return an empty loader
+func NewLoader() *Loader {
+	return &Loader{}
+}
+
+// AddFile add a file layer decoded by its extension, the file must exist
+func (l *Loader) AddFile(filename string) *Loader {
+	return l.addFile(filename, false)
+}
+
+// AddOptionalFile same as AddFile, but skip the file if it not exists
+func (l *Loader) AddOptionalFile(filename string) *Loader {
+	return l.addFile(filename, true)
+}
+
+func (l *Loader) addFile(filename string, optional bool) *Loader {
+	l.layers = append(l.layers, layer{
+		name: filename,
+		load: func(info *loadInfo) (map[string]interface{}, map[string]string, error) {
+			origin, err := parseFile(filename, "", nil, info)
+			if err != nil {
+				if optional && os.IsNotExist(err) {
+					return nil, nil, nil
+				}
+				return nil, nil, err
+			}
+			return origin, map[string]string{"": filename}, nil
+		},
+	})
+	return l
+}
+
+// AddData add a data layer, name is reported by conf.Source,
+// the data is decoded by the extension of name, e.g. "defaults.yaml", commented json if unknown
+func (l *Loader) AddData(name string, data []byte) *Loader {
+	l.layers = append(l.layers, layer{
+		name: name,
+		load: func(info *loadInfo) (map[string]interface{}, map[string]string, error) {
+			origin, err := decode(formatOf(name), data)
+			if err == nil {
+				err = processIncludes(origin, ".", nil, info)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			return origin, map[string]string{"": name}, nil
+		},
+	})
+	return l
+}
+
+// AddEnv add the environment variables which start with prefix as a layer,
+// "__" separate the nested keys, e.g. with prefix "APP_",
+// APP_DB__POOL__MAX=10 override the config "db.pool.max".
+// keys are matched case-insensitively against the lower layers,
+// values which look like a json array or object are decoded as json
+func (l *Loader) AddEnv(prefix string) *Loader {
+	l.layers = append(l.layers, layer{
+		name:     "env:" + prefix,
+		foldKeys: true,
+		load: func(info *loadInfo) (map[string]interface{}, map[string]string, error) {
+			// the env and flag values are not savable into a file
+			info.resolved = true
+			origin := make(map[string]interface{})
+			sources := make(map[string]string)
+			for _, kv := range os.Environ() {
+				i := strings.IndexByte(kv, '=')
+				if i <= len(prefix) || !strings.HasPrefix(kv, prefix) {
+					continue
+				}
+				name, val := kv[:i], kv[i+1:]
+				keys := strings.Split(strings.ToLower(name[len(prefix):]), "__")
+				sources[setKeys(origin, keys, envValue(val))] = "env:" + name
+			}
+			return origin, sources, nil
+		},
+	})
+	return l
+}
+
+func envValue(val string) interface{} {
+	trimed := strings.TrimSpace(val)
+	if strings.HasPrefix(trimed, "[") || strings.HasPrefix(trimed, "{") {
+		var v interface{}
+		if json.Unmarshal([]byte(trimed), &v) == nil {
+			return v
+		}
+	}
+	return val
+}
+
+// AddFlags add the flags which explicitly set in fs as a layer,
+// the flag name is the dotted config path, like "db.pool.max",
+// keys are matched case-insensitively against the lower layers.
+// the bool and number flags keep the value type, the others are stored as f.Value.String()
+func (l *Loader) AddFlags(fs *flag.FlagSet) *Loader {
+	l.layers = append(l.layers, layer{
+		name:     "flag:" + fs.Name(),
+		foldKeys: true,
+		load: func(info *loadInfo) (map[string]interface{}, map[string]string, error) {
+			// the env and flag values are not savable into a file
+			info.resolved = true
+			origin := make(map[string]interface{})
+			sources := make(map[string]string)
+			fs.Visit(func(f *flag.Flag) {
+				// the bools and numbers keep their type, others like time.Duration
+				// use the string form, which the typed getters parse back
+				var val interface{} = f.Value.String()
+				if getter, ok := f.Value.(flag.Getter); ok {
+					switch v := getter.Get().(type) {
+					case bool, int, int64, uint, uint64, float64:
+						val = v
+					}
+				}
+				sources[setKeys(origin, strings.Split(f.Name, "."), val)] = "flag:" + f.Name
+			})
+			return origin, sources, nil
+		},
+	})
+	return l
+}
+
+// Load load and merge all layers into a Config,
+// conf.Reload on the returned config reload all layers
+func (l *Loader) Load(opts ...Option) (*Config, error) {
+	o := newOptions(opts)
+	conf := newConf()
+	conf.keys = o.keys
+	conf.load = func(info *loadInfo) (map[string]interface{}, map[string]string, error) {
+		origin, sources, err := l.load(info)
+		if err == nil {
+			origin, err = o.process(origin, info)
+		}
+		return origin, sources, err
+	}
+	info := &loadInfo{}
+	origin, sources, err := conf.load(info)
+	if err != nil {
+		return nil, err
+	}
+	conf.origin, conf.sources, conf.resolved, conf.files = origin, sources, info.resolved, info.files
+	return conf, nil
+}
+
+func (l *Loader) load(info *loadInfo) (map[string]interface{}, map[string]string, error) {
+	origin := make(map[string]interface{})
+	sources := make(map[string]string)
+	// the merged layers would be flattened into one file on saving
+	if len(l.layers) > 1 {
+		info.resolved = true
+	}
+	for _, ly := range l.layers {
+		lorigin, lsources, err := ly.load(info)
+		if err != nil {
+			return nil, nil, err
+		}
+		if lorigin == nil {
+			continue
+		}
+		deepMerge(origin, lorigin, "", "", ly.foldKeys, sources, lsources)
+	}
+	return origin, sources, nil
+}
+
+// setKeys set val into m with the nested keys, return the dotted path
+func setKeys(m map[string]interface{}, keys []string, val interface{}) string {
+	for _, k := range keys[:len(keys)-1] {
+		sub, ok := m[k].(map[string]interface{})
+		if !ok {
+			sub = make(map[string]interface{})
+			m[k] = sub
+		}
+		m = sub
+	}
+	m[keys[len(keys)-1]] = val
+	return strings.Join(keys, ".")
+}
+
+// deepMerge merge src into dst, objects are merged recursively, other values are replaced.
+// the paths of the replaced values are recorded into sources from srcSources
+func deepMerge(dst, src map[string]interface{}, dstPrefix, srcPrefix string, foldKeys bool, sources, srcSources map[string]string) {
+	for k, sv := range src {
+		dk := k
+		if foldKeys {
+			dk = foldKey(dst, k)
+		}
+		path, srcPath := joinPath(dstPrefix, dk), joinPath(srcPrefix, k)
+
+		srcMap, sIsMap := sv.(map[string]interface{})
+		dstMap, dIsMap := dst[dk].(map[string]interface{})
+		if sIsMap && dIsMap {
+			deepMerge(dstMap, srcMap, path, srcPath, foldKeys, sources, srcSources)
+			continue
+		}
+		if _, ok := dst[dk]; ok {
+			deleteSources(sources, path)
+		}
+		if sIsMap {
+			// copy, so later layers never modify this layer's data
+			dstMap = make(map[string]interface{})
+			deepMerge(dstMap, srcMap, path, srcPath, foldKeys, sources, srcSources)
+			sv = dstMap
+		} else if name, ok := layerSource(srcSources, srcPath); ok {
+			sources[path] = name
+		}
+		dst[dk] = sv
+	}
+}
+
+// foldKey find the key in m which equals k case-insensitively
+func foldKey(m map[string]interface{}, k string) string {
+	if _, ok := m[k]; ok {
+		return k
+	}
+	for mk := range m {
+		if strings.EqualFold(mk, k) {
+			return mk
+		}
+	}
+	return k
+}
+
+func joinPath(prefix, k string) string {
+	if prefix == "" {
+		return k
+	}
+	return prefix + "." + k
+}
+
+// deleteSources remove the records of path and its children
+func deleteSources(sources map[string]string, path string) {
+	for p := range sources {
+		if p == path || strings.HasPrefix(p, path+".") || strings.HasPrefix(p, path+"[") {
+			delete(sources, p)
+		}
+	}
+}
+
+// layerSource find the source of path, or of its nearest recorded parent
+func layerSource(sources map[string]string, path string) (string, bool) {
+	for {
+		if name, ok := sources[path]; ok {
+			return name, true
+		}
+		if path == "" {
+			return "", false
+		}
+		path = parentPath(path)
+	}
+}
+
+// parentPath "a.b[1]" => "a.b", "a.b" => "a", "a" => ""
+func parentPath(path string) string {
+	if i := strings.LastIndexAny(path, ".["); i >= 0 {
+		return path[:i]
+	}
+	return ""
+}
+
+// Source report which layer the value of k came from: a filename,
+// the name given to Loader.AddData, "env:NAME" or "flag:name".
+// return empty string if k not exists or the config has no source
+func (conf *Config) Source(k string) string {
+	conf.cLock.RLock()
+	sources := conf.sources
+	conf.cLock.RUnlock()
+
+	if _, _, ok := conf.lookup(k); !ok {
+		return ""
+	}
+	name, _ := layerSource(sources, k)
+	return name
+}
+
+// GetWithSource same as conf.Get, but also report which layer the value came from
+func (conf *Config) GetWithSource(k string) (interface{}, string) {
+	return conf.Get(k), conf.Source(k)
+}
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLoader(t *testing.T) {
+	base, err := getTempfileWithJSON([]byte(`{
+		// base config
+		"name": "app",
+		"DB": {"host": "localhost", "pool": {"max": 10, "min": 1}},
+		"servers": ["a", "b"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(base)
+
+	os.Setenv("TESTAPP_DB__POOL__MAX", "50")
+	os.Setenv("TESTAPP_SERVERS", `["c"]`)
+	defer os.Unsetenv("TESTAPP_DB__POOL__MAX")
+	defer os.Unsetenv("TESTAPP_SERVERS")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("DB.pool.min", 0, "")
+	fs.String("name", "", "")
+	fs.Duration("DB.timeout", 0, "")
+	fs.String("db.HOST", "", "")
+	if err = fs.Parse([]string{"-DB.pool.min=5", "-DB.timeout=1m30s", "-db.HOST=db.flag"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := NewLoader().
+		AddFile(base).
+		AddOptionalFile(base+".not-exists").
+		AddData("overlay", []byte(`{"DB": {"host": "db.prod" /* prod */}, "debug": true}`)).
+		AddEnv("TESTAPP_").
+		AddFlags(fs).
+		Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		k      string
+		v      interface{}
+		source string
+	}{
+		{"name", "app", base},
+		{"DB.host", "db.flag", "flag:db.HOST"},
+		{"DB.pool.max", "50", "env:TESTAPP_DB__POOL__MAX"},
+		{"DB.pool.min", 5, "flag:DB.pool.min"},
+		{"DB.timeout", "1m30s", "flag:DB.timeout"},
+		{"debug", true, "overlay"},
+		{"servers", []interface{}{"c"}, "env:TESTAPP_SERVERS"},
+		{"servers[0]", "c", "env:TESTAPP_SERVERS"},
+		{"not.exists", nil, ""},
+	}
+	for _, cs := range cases {
+		v, source := conf.GetWithSource(cs.k)
+		if !reflect.DeepEqual(v, cs.v) || source != cs.source {
+			t.Fatalf("conf.GetWithSource(%s) = %#v, %s; want %#v, %s", cs.k, v, source, cs.v, cs.source)
+		}
+	}
+	if conf.Int("DB.pool.max") != 50 {
+		t.Fatal(`conf.Int("DB.pool.max") != 50`)
+	}
+	if d := conf.GetDuration("DB.timeout"); d != 90*time.Second {
+		t.Fatalf(`conf.GetDuration("DB.timeout") = %s`, d)
+	}
+
+	_, err = NewLoader().AddFile(base + ".not-exists").Load()
+	if !os.IsNotExist(err) {
+		t.Fatalf("required file not exists should fail, got: %v", err)
+	}
+
+	os.Setenv("TESTAPP_DB__POOL__MAX", "60")
+	if err = conf.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Int("DB.pool.max") != 60 {
+		t.Fatal(`reload: conf.Int("DB.pool.max") != 60`)
+	}
+}
+
+func TestConfigSource(t *testing.T) {
+	tmpfile, err := getTempfileWithJSON([]byte(`{"a": {"b": 1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile)
+
+	conf, err := ParseFromFile(tmpfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Source("a.b") != tmpfile {
+		t.Fatalf(`conf.Source("a.b") = %s, want %s`, conf.Source("a.b"), tmpfile)
+	}
+	if conf.Source("a.c") != "" {
+		t.Fatal(`conf.Source("a.c") should be empty`)
+	}
+}
+
+func TestLoaderFoldKeys(t *testing.T) {
+	os.Setenv("TESTFOLD_DB__POOL__MAX", "20")
+	os.Setenv("TESTFOLD_DB__POOL__MIN", "2")
+	defer os.Unsetenv("TESTFOLD_DB__POOL__MAX")
+	defer os.Unsetenv("TESTFOLD_DB__POOL__MIN")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("db.POOL.max", 0, "")
+	if err := fs.Parse([]string{"-db.POOL.max=30"}); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := NewLoader().
+		AddData("base.json", []byte(`{"DB": {"pool": {"max": 10, "min": 1, "idle": 5}}}`)).
+		AddEnv("TESTFOLD_").
+		AddFlags(fs).
+		Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the later layers override the same key of any case, no new trees
+	want := map[string]interface{}{"DB": map[string]interface{}{"pool": map[string]interface{}{"max": 30, "min": "2", "idle": 5.0}}}
+	if !reflect.DeepEqual(conf.origin, want) {
+		t.Fatalf("got %#v\nwant %#v", conf.origin, want)
+	}
+	if v, source := conf.GetWithSource("DB.pool.max"); v != 30 || source != "flag:db.POOL.max" {
+		t.Fatalf(`conf.GetWithSource("DB.pool.max") = %#v, %s`, v, source)
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+// Option change how a config is parsed
+type Option func(*options)
+
+type options struct {
+	schema *Schema
+	format string
+	keys   KeyProvider
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithSchema validate the config against s after parsed and on every reload
+func WithSchema(s *Schema) Option {
+	return func(o *options) {
+		o.schema = s
+	}
+}
+
+// process resolve the "${...}" references in the freshly parsed origin data
+// then check it against the schema
+func (o *options) process(origin map[string]interface{}, info *loadInfo) (map[string]interface{}, error) {
+	origin, err := interpolate(origin, info)
+	if err != nil {
+		return nil, err
+	}
+	if o.schema != nil {
+		if err = o.schema.Validate(origin); err != nil {
+			return nil, err
+		}
+	}
+	return origin, nil
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// pathSegment is one step of a config path: a map key or a slice index
+type pathSegment struct {
+	key   string
+	index int
+	isIdx bool
+}
+
+// parsePath split a config path like "servers[2].host" into segments
+func parsePath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	if path == "" {
+		return segs, nil
+	}
+	for _, part := range strings.Split(path, ".") {
+		// key part, before the first "["
+		key := part
+		idxPart := ""
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key, idxPart = part[:i], part[i:]
+		}
+		if key == "" && (idxPart == "" || len(segs) > 0) {
+			return nil, fmt.Errorf("config path %q has an empty key", path)
+		}
+		if key != "" {
+			segs = append(segs, pathSegment{key: key})
+		}
+		// index parts, like "[2][0]"
+		for idxPart != "" {
+			end := strings.IndexByte(idxPart, ']')
+			if idxPart[0] != '[' || end < 0 {
+				return nil, fmt.Errorf("config path %q has an unclosed index", path)
+			}
+			idx, err := strconv.Atoi(idxPart[1:end])
+			if err != nil || idx < 0 {
+				return nil, fmt.Errorf("config path %q has an invalid index: %s", path, idxPart[:end+1])
+			}
+			segs = append(segs, pathSegment{index: idx, isIdx: true})
+			idxPart = idxPart[end+1:]
+		}
+	}
+	return segs, nil
+}
+
+// lookupPath walk through nested maps and slices by the given path
+func lookupPath(root map[string]interface{}, path string) (interface{}, bool) {
+	return lookupValue(root, path)
+}
+
+// lookupValue same as lookupPath, but start from any value
+func lookupValue(cur interface{}, path string) (interface{}, bool) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, false
+	}
+	for _, seg := range segs {
+		if seg.isIdx {
+			sv, ok := cur.([]interface{})
+			if !ok || seg.index >= len(sv) {
+				return nil, false
+			}
+			cur = sv[seg.index]
+			continue
+		}
+		mv, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = mv[seg.key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
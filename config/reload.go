@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ErrNotReloadable the config is not parsed from a file, so it can not be reloaded
+var ErrNotReloadable = errors.New("config: not parsed from a file, can not reload")
+
+// ChangeFunc is called with the old and new value of the watched key
+// after a reload changed it, old or new is nil if the key not exists
+type ChangeFunc func(k string, old, new interface{})
+
+type subscriber struct {
+	k  string
+	fn ChangeFunc
+}
+
+// OnChange register fn to be called when the value of k changed by a reload
+// k can be a dotted path, an empty k watch the whole config
+func (conf *Config) OnChange(k string, fn ChangeFunc) {
+	conf.sLock.Lock()
+	conf.subs = append(conf.subs, subscriber{k: k, fn: fn})
+	conf.sLock.Unlock()
+}
+
+// Reload re-parse the config file, swap the config data and drop all cached values
+// if parse failed, the last good config is kept and the error is returned.
+// the concurrent reloads run one by one, the OnChange callbacks are called in the reload order,
+// so the callbacks must not call conf.Reload
+func (conf *Config) Reload() error {
+	if conf.load == nil {
+		return ErrNotReloadable
+	}
+	conf.rLock.Lock()
+	defer conf.rLock.Unlock()
+
+	info := &loadInfo{}
+	origin, sources, err := conf.load(info)
+	if err != nil {
+		return err
+	}
+
+	conf.cLock.Lock()
+	old := conf.origin
+	conf.origin, conf.sources, conf.resolved, conf.files = origin, sources, info.resolved, info.files
+	conf.resetCache()
+	conf.cLock.Unlock()
+
+	conf.notify(old, origin)
+	return nil
+}
+
+func (conf *Config) notify(old, origin map[string]interface{}) {
+	conf.sLock.Lock()
+	subs := make([]subscriber, len(conf.subs))
+	copy(subs, conf.subs)
+	conf.sLock.Unlock()
+
+	for _, sub := range subs {
+		if sub.k == "" {
+			if !reflect.DeepEqual(old, origin) {
+				sub.fn(sub.k, old, origin)
+			}
+			continue
+		}
+		oldv, _ := lookupPath(old, sub.k)
+		newv, _ := lookupPath(origin, sub.k)
+		if !reflect.DeepEqual(oldv, newv) {
+			sub.fn(sub.k, oldv, newv)
+		}
+	}
+}
+
+// Watch check the config files every interval and reload the config when one changed,
+// include being replaced by a rename. the files are the ones of the last load:
+// the parsed file, the Loader file layers and their "@include" files.
+// reload errors are passed to onError (if not nil).
+// call the returned stop function to stop watching
+func (conf *Config) Watch(interval time.Duration, onError func(err error)) (stop func()) {
+	done := make(chan struct{})
+	last := make(map[string]os.FileInfo)
+	for _, f := range conf.watchFiles() {
+		last[f], _ = os.Stat(f)
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			changed := false
+			for _, f := range conf.watchFiles() {
+				fi, err := os.Stat(f)
+				if err != nil {
+					// the file may be in the middle of a replace or an optional one, check it next time
+					continue
+				}
+				if old := last[f]; old == nil || !os.SameFile(old, fi) || !fi.ModTime().Equal(old.ModTime()) || fi.Size() != old.Size() {
+					changed = true
+				}
+				last[f] = fi
+			}
+			if !changed {
+				continue
+			}
+			if err := conf.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+			// the files newly included by the reload
+			for _, f := range conf.watchFiles() {
+				if _, ok := last[f]; !ok {
+					last[f], _ = os.Stat(f)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { close(done) })
+	}
+}
+
+// watchFiles return the config files of the last load
+func (conf *Config) watchFiles() []string {
+	conf.cLock.RLock()
+	defer conf.cLock.RUnlock()
+	return conf.files
+}
@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/iyidan/goutils/mise"
+)
+
+func TestConfigReload(t *testing.T) {
+	tmpfile, err := getTempfileWithJSON([]byte(`{"db": {"max": 10}, "name": "a"} // v1`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile)
+
+	conf, err := ParseFromFile(tmpfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Int("db.max") != 10 {
+		t.Fatal(`conf.Int("db.max") != 10`)
+	}
+
+	var changes []string
+	conf.OnChange("db.max", func(k string, old, new interface{}) {
+		changes = append(changes, k)
+		if old.(float64) != 10 || new.(float64) != 20 {
+			t.Fatalf("OnChange(%s): old=%v, new=%v", k, old, new)
+		}
+	})
+	conf.OnChange("name", func(k string, old, new interface{}) {
+		changes = append(changes, k)
+	})
+
+	err = ioutil.WriteFile(tmpfile, []byte(`{"db": {"max": 20}, "name": "a"} // v2`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Int("db.max") != 20 {
+		t.Fatal(`cached conf.Int("db.max") not invalidated`)
+	}
+	if len(changes) != 1 || changes[0] != "db.max" {
+		t.Fatalf("changes: %v", changes)
+	}
+
+	// a broken file keeps the last good config
+	err = ioutil.WriteFile(tmpfile, []byte(`{"db": {"max": 30}`), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Reload(); err == nil {
+		t.Fatal("reload a broken file should fail")
+	}
+	if conf.Int("db.max") != 20 {
+		t.Fatal("last good config not kept")
+	}
+
+	dataConf, err := ParseFromData([]byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataConf.Reload() != ErrNotReloadable {
+		t.Fatal("config from data should not be reloadable")
+	}
+}
+
+func TestConfigReloadConcurrent(t *testing.T) {
+	tmpfile, err := getTempfileWithJSON([]byte(`{"v": 0}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile)
+	conf, err := ParseFromFile(tmpfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int
+	conf.OnChange("v", func(k string, old, new interface{}) {
+		got = append(got, int(new.(float64)))
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 50; i++ {
+			if err := mise.WriteFileAtomic(tmpfile, []byte(fmt.Sprintf(`{"v": %d}`, i)), 0644); err != nil {
+				t.Error(err)
+				return
+			}
+			conf.Reload()
+		}
+	}()
+	for running := true; running; {
+		select {
+		case <-done:
+			running = false
+		default:
+			conf.Reload()
+		}
+	}
+
+	if conf.Int("v") != 50 {
+		t.Fatalf(`conf.Int("v") = %d`, conf.Int("v"))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("the changes out of order: %v", got)
+		}
+	}
+}
+
+func TestConfigWatch(t *testing.T) {
+	tmpfile, err := getTempfileWithJSON([]byte(`{"v": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile)
+
+	conf, err := ParseFromFile(tmpfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan interface{}, 10)
+	conf.OnChange("v", func(k string, old, new interface{}) {
+		changed <- new
+	})
+	errs := make(chan error, 10)
+	stop := conf.Watch(5*time.Millisecond, func(err error) {
+		errs <- err
+	})
+	defer stop()
+
+	wait := func() {
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case <-changed:
+				return
+			case <-errs:
+				// the watcher may see a half written file, it reloads again on the next change
+			case <-timeout:
+				t.Fatal("change not detected")
+			}
+		}
+	}
+
+	// replace by rename
+	newfile, err := getTempfileWithJSON([]byte(`{"v": 2} # renamed`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.Rename(newfile, tmpfile); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	if conf.Int("v") != 2 {
+		t.Fatal(`conf.Int("v") != 2`)
+	}
+
+	// rewrite in place
+	if err = ioutil.WriteFile(tmpfile, []byte(`{"v": 3, "padding": true}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wait()
+	if conf.Int("v") != 3 {
+		t.Fatal(`conf.Int("v") != 3`)
+	}
+
+	// broken file reports error
+	if err = ioutil.WriteFile(tmpfile, []byte(`{"v": `), 0600); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-errs:
+	case <-time.After(2 * time.Second):
+		t.Fatal("reload error not reported")
+	}
+	if conf.Int("v") != 3 {
+		t.Fatal("last good config not kept")
+	}
+}
+
+func TestConfigWatchIncludes(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"main.json": `{"@include": "db.json", "name": "app"}`,
+		"db.json":   `{"db": {"max": 1}}`,
+	})
+	defer os.RemoveAll(dir)
+
+	conf, err := NewLoader().
+		AddFile(filepath.Join(dir, "main.json")).
+		AddOptionalFile(filepath.Join(dir, "local.json")).
+		Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := make(chan interface{}, 10)
+	conf.OnChange("db.max", func(k string, old, new interface{}) {
+		changed <- new
+	})
+	stop := conf.Watch(5*time.Millisecond, nil)
+	defer stop()
+
+	wait := func(want int) {
+		timeout := time.After(2 * time.Second)
+		for conf.Int("db.max") != want {
+			select {
+			case <-changed:
+			case <-timeout:
+				t.Fatalf("change to %d not detected", want)
+			}
+		}
+	}
+
+	// the included file
+	if err = ioutil.WriteFile(filepath.Join(dir, "db.json"), []byte(`{"db": {"max": 2}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wait(2)
+
+	// the optional file layer created later
+	if err = ioutil.WriteFile(filepath.Join(dir, "local.json"), []byte(`{"db": {"max": 3}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wait(3)
+}
@@ -0,0 +1,251 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/iyidan/goutils/mise"
+)
+
+// IncludeKey is the directive key to include other config files into an object,
+// the value is a filename or a list of filenames relative to the including file,
+// the included files are decoded by their extensions,
+// keys of the including object override the included ones:
+//
+//	{"@include": ["common.json", "db.json"], "name": "app"}
+const IncludeKey = "@include"
+
+// loadInfo record how a load built the origin data
+type loadInfo struct {
+	files    []string // the absolute names of the files read, the included ones too
+	resolved bool     // "@include", "${...}", the env/flag layers or merging layers changed the data
+}
+
+// parseFile parse a config file and process its "@include" directives,
+// the file is decoded by format, by its extension if format is empty.
+// stack is the including chain, used to detect include cycles
+func parseFile(filename string, format string, stack []string, info *loadInfo) (map[string]interface{}, error) {
+	absname, err := filepath.Abs(filename)
+	if err != nil {
+		return nil, err
+	}
+	for i := range stack {
+		if stack[i] == absname {
+			return nil, fmt.Errorf("config: include cycle: %s -> %s", strings.Join(stack[i:], " -> "), absname)
+		}
+	}
+	// recorded before decoding, so an optional file not exists yet is watched too
+	info.files = append(info.files, absname)
+
+	origin, err := decodeFile(filename, format)
+	if err != nil {
+		if len(stack) > 0 {
+			return nil, mise.WrapErrorMsg(err, "config: include "+filename)
+		}
+		return nil, err
+	}
+	if err = processIncludes(origin, filepath.Dir(absname), append(stack, absname), info); err != nil {
+		return nil, err
+	}
+	return origin, nil
+}
+
+// processIncludes replace the "@include" directives in m and its children with the included data
+func processIncludes(m map[string]interface{}, dir string, stack []string, info *loadInfo) error {
+	for _, v := range m {
+		if err := processIncludesValue(v, dir, stack, info); err != nil {
+			return err
+		}
+	}
+
+	inc, ok := m[IncludeKey]
+	if !ok {
+		return nil
+	}
+	delete(m, IncludeKey)
+	info.resolved = true
+
+	var filenames []string
+	switch tv := inc.(type) {
+	case string:
+		filenames = []string{tv}
+	case []interface{}:
+		for _, f := range tv {
+			s, ok := f.(string)
+			if !ok {
+				return &TypeError{Key: IncludeKey, Want: "string", Value: f}
+			}
+			filenames = append(filenames, s)
+		}
+	default:
+		return &TypeError{Key: IncludeKey, Want: "string or []string", Value: inc}
+	}
+
+	merged := make(map[string]interface{})
+	for _, filename := range filenames {
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+		included, err := parseFile(filename, "", stack, info)
+		if err != nil {
+			return err
+		}
+		deepMerge(merged, included, "", "", false, map[string]string{}, nil)
+	}
+	// own keys override the included ones
+	deepMerge(merged, m, "", "", false, map[string]string{}, nil)
+	for k, v := range merged {
+		m[k] = v
+	}
+	return nil
+}
+
+func processIncludesValue(v interface{}, dir string, stack []string, info *loadInfo) error {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		return processIncludes(tv, dir, stack, info)
+	case []interface{}:
+		for i := range tv {
+			if err := processIncludesValue(tv[i], dir, stack, info); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// interpolate resolve all the "${...}" references in origin, return a resolved copy:
+//
+//	"${db.host}"              the value of another config path, keep its type if it is the whole string
+//	"${db.host:-localhost}"   with a default value if the path not exists
+//	"${env:HOME}"             an environment variable, must be set
+//	"${env:HOME:-/root}"      an environment variable with a default value
+//	"$${literal}"             escape, result in "${literal}"
+//
+// an "ENC[AES256_GCM,...]" value is decrypted when read, so it can only be referenced as the whole string
+func interpolate(origin map[string]interface{}, info *loadInfo) (map[string]interface{}, error) {
+	r := &resolver{root: origin, info: info}
+	v, err := r.resolve("", origin)
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]interface{}), nil
+}
+
+type resolver struct {
+	root  map[string]interface{}
+	stack []string // the references being resolved, to detect cycles
+	info  *loadInfo
+}
+
+func (r *resolver) resolve(path string, v interface{}) (interface{}, error) {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		nm := make(map[string]interface{}, len(tv))
+		for k, mv := range tv {
+			rv, err := r.resolve(joinPath(path, k), mv)
+			if err != nil {
+				return nil, err
+			}
+			nm[k] = rv
+		}
+		return nm, nil
+	case []interface{}:
+		ns := make([]interface{}, len(tv))
+		for i := range tv {
+			rv, err := r.resolve(fmt.Sprintf("%s[%d]", path, i), tv[i])
+			if err != nil {
+				return nil, err
+			}
+			ns[i] = rv
+		}
+		return ns, nil
+	case string:
+		if strings.Contains(tv, "${") {
+			r.info.resolved = true
+			return r.expand(path, tv)
+		}
+	}
+	return v, nil
+}
+
+// expand resolve the references in the string s of path
+func (r *resolver) expand(path string, s string) (interface{}, error) {
+	var buf strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			buf.WriteString(s)
+			break
+		}
+		// "$${" is an escaped "${"
+		if i > 0 && s[i-1] == '$' {
+			buf.WriteString(s[:i-1])
+			buf.WriteString("${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("config: %s: unclosed reference in %q", path, s)
+		}
+		val, err := r.reference(path, s[i+2:i+end])
+		if err != nil {
+			return nil, err
+		}
+		// a whole string reference keep the referenced value type
+		if i == 0 && end == len(s)-1 && buf.Len() == 0 {
+			return val, nil
+		}
+		// the encrypted values are decrypted when read, a part of a string is never decrypted
+		if !reflect.DeepEqual(redact(val), val) {
+			return nil, fmt.Errorf("config: %s: reference ${%s} to an encrypted value can not be a part of a string", path, s[i+2:i+end])
+		}
+		buf.WriteString(s[:i])
+		if sv, ok := val.(string); ok {
+			buf.WriteString(sv)
+		} else {
+			fmt.Fprint(&buf, val)
+		}
+		s = s[i+end+1:]
+	}
+	return buf.String(), nil
+}
+
+// reference resolve one reference like "db.host:-localhost" or "env:HOME"
+func (r *resolver) reference(path string, ref string) (interface{}, error) {
+	name, def, hasDef := ref, "", false
+	if i := strings.Index(ref, ":-"); i >= 0 {
+		name, def, hasDef = ref[:i], ref[i+2:], true
+	}
+
+	if strings.HasPrefix(name, "env:") {
+		if v, ok := os.LookupEnv(name[len("env:"):]); ok {
+			return v, nil
+		}
+		if hasDef {
+			return def, nil
+		}
+		return nil, fmt.Errorf("config: %s: environment variable %s not set", path, name[len("env:"):])
+	}
+
+	raw, ok := lookupPath(r.root, name)
+	if !ok {
+		if hasDef {
+			return def, nil
+		}
+		return nil, fmt.Errorf("config: %s: reference ${%s} not exists", path, name)
+	}
+	for i := range r.stack {
+		if r.stack[i] == name {
+			return nil, fmt.Errorf("config: reference cycle: %s -> %s", strings.Join(r.stack[i:], " -> "), name)
+		}
+	}
+	r.stack = append(r.stack, name)
+	v, err := r.resolve(name, raw)
+	r.stack = r.stack[:len(r.stack)-1]
+	return v, err
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeTestFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "configResolve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range files {
+		filename := filepath.Join(dir, name)
+		if err = os.MkdirAll(filepath.Dir(filename), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err = ioutil.WriteFile(filename, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestConfigInclude(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"main.json": `{
+			"@include": ["common/base.json", "common/db.json"], // common config
+			"name": "main",
+			"db": {"pool": 20}
+		}`,
+		"common/base.json": `{"name": "base", "region": "cn", "log": {"@include": "log.json"}}`,
+		"common/log.json":  `{"level": "info"} # relative to common/`,
+		"common/db.json":   `{"db": {"host": "localhost", "pool": 10}}`,
+	})
+	defer os.RemoveAll(dir)
+
+	conf, err := ParseFromFile(filepath.Join(dir, "main.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"name":   "main",
+		"region": "cn",
+		"log":    map[string]interface{}{"level": "info"},
+		"db":     map[string]interface{}{"host": "localhost", "pool": float64(20)},
+	}
+	if !reflect.DeepEqual(conf.origin, want) {
+		t.Fatalf("include result: %#v\nwant: %#v", conf.origin, want)
+	}
+}
+
+func TestConfigIncludeErrors(t *testing.T) {
+	dir := writeTestFiles(t, map[string]string{
+		"a.json":       `{"@include": "b.json"}`,
+		"b.json":       `{"@include": "a.json"}`,
+		"missing.json": `{"@include": "not-exists.json"}`,
+		"badtype.json": `{"@include": 1}`,
+	})
+	defer os.RemoveAll(dir)
+
+	cases := map[string]string{
+		"a.json":       "include cycle",
+		"missing.json": "not-exists.json",
+		"badtype.json": IncludeKey,
+	}
+	for name, msg := range cases {
+		_, err := ParseFromFile(filepath.Join(dir, name))
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("%s: want error contains %q, got: %v", name, msg, err)
+		}
+	}
+}
+
+func TestConfigInterpolate(t *testing.T) {
+	os.Setenv("TEST_CONFIG_HOME", "/home/test")
+	defer os.Unsetenv("TEST_CONFIG_HOME")
+
+	conf, err := ParseFromData([]byte(`{
+		"host": "example.com",
+		"port": 8080,
+		"url": "http://${host}:${port}/api",
+		"port2": "${port}",
+		"db": {"host": "${host}", "tags": ["a"]},
+		"db2": "${db}",
+		"home": "${env:TEST_CONFIG_HOME}/data",
+		"tmp": "${env:TEST_CONFIG_NOT_EXISTS:-/tmp}",
+		"timeout": "${db.timeout:-3s}",
+		"literal": "$${host}"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]interface{}{
+		"url":     "http://example.com:8080/api",
+		"port2":   float64(8080),
+		"db.host": "example.com",
+		"db2":     map[string]interface{}{"host": "example.com", "tags": []interface{}{"a"}},
+		"home":    "/home/test/data",
+		"tmp":     "/tmp",
+		"timeout": "3s",
+		"literal": "${host}",
+	}
+	for k, v := range cases {
+		if got := conf.Get(k); !reflect.DeepEqual(got, v) {
+			t.Fatalf("conf.Get(%s) = %#v, want %#v", k, got, v)
+		}
+	}
+
+	for data, msg := range map[string]string{
+		`{"a": "${b}", "b": "${a}"}`:             "reference cycle",
+		`{"a": "${a}"}`:                          "reference cycle",
+		`{"a": "${b}"}`:                          "${b} not exists",
+		`{"a": "${env:TEST_CONFIG_NOT_EXISTS}"}`: "TEST_CONFIG_NOT_EXISTS not set",
+		`{"a": "${b"}`:                           "unclosed",
+	} {
+		_, err := ParseFromData([]byte(data))
+		if err == nil || !strings.Contains(err.Error(), msg) {
+			t.Fatalf("%s: want error contains %q, got: %v", data, msg, err)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/iyidan/goutils/cmtjson"
+)
+
+// Schema is a subset of JSON Schema to validate a config:
+// type, properties, required, additionalProperties, items, minItems, maxItems,
+// minimum, maximum, exclusiveMinimum, exclusiveMaximum, enum, pattern, minLength and maxLength.
+// type can be a string or a list of "object", "array", "string", "number", "integer", "boolean" and "null"
+type Schema struct {
+	Type                 interface{}        `json:"type,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *bool              `json:"additionalProperties,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64           `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64           `json:"exclusiveMaximum,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+
+	types      []string
+	pattern    *regexp.Regexp
+	once       sync.Once
+	compileErr error
+}
+
+// SchemaViolation is one schema check failure of a config value
+type SchemaViolation struct {
+	Path    string
+	Message string
+}
+
+func (v SchemaViolation) String() string {
+	path := v.Path
+	if path == "" {
+		path = "(root)"
+	}
+	return path + ": " + v.Message
+}
+
+// SchemaError collect all the violations of a schema validation
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.String()
+	}
+	return "config schema violations: " + strings.Join(msgs, "; ")
+}
+
+// ParseSchemaFromFile parse a schema from a commented json file
+func ParseSchemaFromFile(filename string) (*Schema, error) {
+	s := &Schema{}
+	if err := cmtjson.ParseFromFile(filename, s); err != nil {
+		return nil, err
+	}
+	if err := s.prepare(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ParseSchemaFromData parse a schema from commented json data
+func ParseSchemaFromData(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := cmtjson.ParseFromBytes(data, s); err != nil {
+		return nil, err
+	}
+	if err := s.prepare(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+var schemaTypes = map[string]bool{
+	"object": true, "array": true, "string": true, "number": true, "integer": true, "boolean": true, "null": true,
+}
+
+// prepare compile the schema only once
+func (s *Schema) prepare() error {
+	s.once.Do(func() {
+		s.compileErr = s.compile("")
+	})
+	return s.compileErr
+}
+
+// compile check the schema and prepare the types and pattern
+func (s *Schema) compile(path string) error {
+	s.types = nil
+	switch t := s.Type.(type) {
+	case nil:
+	case string:
+		s.types = []string{t}
+	case []interface{}:
+		for _, it := range t {
+			st, ok := it.(string)
+			if !ok {
+				return fmt.Errorf("config schema %s: invalid type %#v", path, s.Type)
+			}
+			s.types = append(s.types, st)
+		}
+	case []string:
+		s.types = t
+	default:
+		return fmt.Errorf("config schema %s: invalid type %#v", path, s.Type)
+	}
+	for _, t := range s.types {
+		if !schemaTypes[t] {
+			return fmt.Errorf("config schema %s: unknown type %q", path, t)
+		}
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("config schema %s: invalid pattern: %s", path, err)
+		}
+		s.pattern = re
+	}
+	for k, ps := range s.Properties {
+		if ps == nil {
+			return fmt.Errorf("config schema %s: property %s is null", path, k)
+		}
+		if err := ps.compile(joinPath(path, k)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// Validate check v (decoded json data) against the schema,
+// return a *SchemaError with all the violations
+func (s *Schema) Validate(v interface{}) error {
+	if err := s.prepare(); err != nil {
+		return err
+	}
+	var vs []SchemaViolation
+	s.validate("", v, &vs)
+	if len(vs) > 0 {
+		return &SchemaError{Violations: vs}
+	}
+	return nil
+}
+
+func jsonType(v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		if tv == math.Trunc(tv) && !math.IsInf(tv, 0) {
+			return "integer"
+		}
+		return "number"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func (s *Schema) validate(path string, v interface{}, vs *[]SchemaViolation) {
+	addf := func(format string, args ...interface{}) {
+		*vs = append(*vs, SchemaViolation{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.types) > 0 {
+		vt := jsonType(v)
+		matched := false
+		for _, t := range s.types {
+			if t == vt || (t == "number" && vt == "integer") {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			addf("want type %s, got %s", strings.Join(s.types, "|"), vt)
+			return
+		}
+	}
+
+	if len(s.Enum) > 0 {
+		found := false
+		for _, e := range s.Enum {
+			if reflect.DeepEqual(e, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			addf("%#v not in enum %v", v, s.Enum)
+		}
+	}
+
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		for _, k := range s.Required {
+			if _, ok := tv[k]; !ok {
+				*vs = append(*vs, SchemaViolation{Path: joinPath(path, k), Message: "required but missing"})
+			}
+		}
+		keys := make([]string, 0, len(tv))
+		for k := range tv {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if ps, ok := s.Properties[k]; ok {
+				ps.validate(joinPath(path, k), tv[k], vs)
+			} else if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				*vs = append(*vs, SchemaViolation{Path: joinPath(path, k), Message: "additional property not allowed"})
+			}
+		}
+	case []interface{}:
+		if s.MinItems != nil && len(tv) < *s.MinItems {
+			addf("want at least %d items, got %d", *s.MinItems, len(tv))
+		}
+		if s.MaxItems != nil && len(tv) > *s.MaxItems {
+			addf("want at most %d items, got %d", *s.MaxItems, len(tv))
+		}
+		if s.Items != nil {
+			for i := range tv {
+				s.Items.validate(fmt.Sprintf("%s[%d]", path, i), tv[i], vs)
+			}
+		}
+	case string:
+		n := len([]rune(tv))
+		if s.MinLength != nil && n < *s.MinLength {
+			addf("want at least %d characters, got %d", *s.MinLength, n)
+		}
+		if s.MaxLength != nil && n > *s.MaxLength {
+			addf("want at most %d characters, got %d", *s.MaxLength, n)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(tv) {
+			addf("%q not match pattern %q", tv, s.Pattern)
+		}
+	case float64:
+		if s.Minimum != nil && tv < *s.Minimum {
+			addf("%v less than minimum %v", tv, *s.Minimum)
+		}
+		if s.Maximum != nil && tv > *s.Maximum {
+			addf("%v greater than maximum %v", tv, *s.Maximum)
+		}
+		if s.ExclusiveMinimum != nil && tv <= *s.ExclusiveMinimum {
+			addf("%v not greater than exclusive minimum %v", tv, *s.ExclusiveMinimum)
+		}
+		if s.ExclusiveMaximum != nil && tv >= *s.ExclusiveMaximum {
+			addf("%v not less than exclusive maximum %v", tv, *s.ExclusiveMaximum)
+		}
+	}
+}
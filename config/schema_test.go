@@ -0,0 +1,107 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+var testSchema = `{
+	# the whole config
+	"type": "object",
+	"required": ["name", "db"],
+	"properties": {
+		"name": {"type": "string", "pattern": "^[a-z]+$", "maxLength": 8},
+		"env":  {"enum": ["dev", "prod"]},
+		"db": {
+			"type": "object",
+			"required": ["host"],
+			"additionalProperties": false,
+			"properties": {
+				"host": {"type": "string", "minLength": 1},
+				"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+				"ratio": {"type": "number", "exclusiveMaximum": 1}
+			}
+		},
+		/* nested arrays */
+		"servers": {
+			"type": "array",
+			"minItems": 1,
+			"items": {"type": ["string", "null"]}
+		}
+	}
+}`
+
+func TestSchema(t *testing.T) {
+	schema, err := ParseSchemaFromData([]byte(testSchema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseFromData([]byte(`{
+		"name": "app", "env": "dev",
+		"db": {"host": "localhost", "port": 3306, "ratio": 0.5},
+		"servers": ["a", null]
+	}`), WithSchema(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ParseFromData([]byte(`{
+		"name": "App1", "env": "test",
+		"db": {"port": 3306.5, "ratio": 1, "user": "root"},
+		"servers": ["a", 1]
+	}`), WithSchema(schema))
+	var serr *SchemaError
+	if !errors.As(err, &serr) {
+		t.Fatalf("want *SchemaError, got: %v", err)
+	}
+	paths := make([]string, len(serr.Violations))
+	for i, v := range serr.Violations {
+		paths[i] = v.Path
+	}
+	want := []string{"db.host", "db.port", "db.ratio", "db.user", "env", "name", "servers[1]"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("violation paths: %v, want: %v\n%s", paths, want, err)
+	}
+
+	_, err = ParseFromData([]byte(`{"servers": []}`), WithSchema(schema))
+	if !errors.As(err, &serr) || len(serr.Violations) != 3 {
+		t.Fatalf("want 3 violations, got: %v", err)
+	}
+
+	// validate on reload too
+	tmpfile, err := getTempfileWithJSON([]byte(`{"name": "app", "db": {"host": "h"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile)
+	conf, err := ParseFromFile(tmpfile, WithSchema(schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(tmpfile, []byte(`{"name": "app"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Reload(); !errors.As(err, &serr) {
+		t.Fatalf("reload should validate the schema, got: %v", err)
+	}
+	if conf.String("db.host") != "h" {
+		t.Fatal("last good config not kept")
+	}
+}
+
+func TestSchemaInvalid(t *testing.T) {
+	for _, data := range []string{
+		`{"type": "int"}`,
+		`{"type": 1}`,
+		`{"properties": {"a": {"pattern": "("}}}`,
+		`{"items": {"type": ["string", 1]}}`,
+	} {
+		if _, err := ParseSchemaFromData([]byte(data)); err == nil {
+			t.Fatalf("schema %s should be invalid", data)
+		}
+	}
+}
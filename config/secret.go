@@ -0,0 +1,257 @@
+package config
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// encrypted value markers, a value looks like "ENC[AES256_GCM,<base64 of nonce and sealed data>]"
+const (
+	encPrefix = "ENC[AES256_GCM,"
+	encSuffix = "]"
+)
+
+// Redacted replace the encrypted values in conf.Dump
+const Redacted = "<redacted>"
+
+// KeyProvider supply the AES-256 key to decrypt the "ENC[AES256_GCM,...]" values
+type KeyProvider interface {
+	Key() ([]byte, error)
+}
+
+// KeyProviderFunc adapt a function to a KeyProvider
+type KeyProviderFunc func() ([]byte, error)
+
+// Key call f
+func (f KeyProviderFunc) Key() ([]byte, error) {
+	return f()
+}
+
+// EnvKey read the base64 encoded key from the environment variable name
+func EnvKey(name string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		s, ok := os.LookupEnv(name)
+		if !ok {
+			return nil, fmt.Errorf("config: key environment variable %s not set", name)
+		}
+		return DecodeKey(s)
+	})
+}
+
+// FileKey read the base64 encoded key from filename
+func FileKey(filename string) KeyProvider {
+	return KeyProviderFunc(func() ([]byte, error) {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		return DecodeKey(string(data))
+	})
+}
+
+// WithKeyProvider decrypt the encrypted values with the key of kp
+func WithKeyProvider(kp KeyProvider) Option {
+	return func(o *options) {
+		o.keys = kp
+	}
+}
+
+// GenerateKey return a random base64 encoded AES-256 key
+func GenerateKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// DecodeKey decode a base64 encoded AES-256 key
+func DecodeKey(s string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("config: invalid key: %s", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("config: invalid key: want 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+// IsEncrypted report whether s is an encrypted value
+func IsEncrypted(s string) bool {
+	return strings.HasPrefix(s, encPrefix) && strings.HasSuffix(s, encSuffix)
+}
+
+// Encrypt encrypt plaintext with key into an "ENC[AES256_GCM,...]" value
+func Encrypt(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return encPrefix + base64.StdEncoding.EncodeToString(sealed) + encSuffix, nil
+}
+
+// Decrypt decrypt an "ENC[AES256_GCM,...]" value with key
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", fmt.Errorf("config: not an encrypted value")
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(value[len(encPrefix) : len(value)-len(encSuffix)])
+	if err != nil || len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("config: malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("config: decrypt: %s", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// decrypt decrypt the encrypted value s of path k
+func (conf *Config) decrypt(k string, s string) (string, error) {
+	if conf.keys == nil {
+		return "", fmt.Errorf("config: %s is encrypted, but no key provider", k)
+	}
+	key, err := conf.keys.Key()
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := Decrypt(key, s)
+	if err != nil {
+		return "", fmt.Errorf("config: %s: %s", k, err)
+	}
+	return plaintext, nil
+}
+
+// decryptTree return a copy of v of path k with the encrypted values decrypted
+func (conf *Config) decryptTree(k string, v interface{}) (interface{}, error) {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		nm := make(map[string]interface{}, len(tv))
+		for mk, mv := range tv {
+			nv, err := conf.decryptTree(joinPath(k, mk), mv)
+			if err != nil {
+				return nil, err
+			}
+			nm[mk] = nv
+		}
+		return nm, nil
+	case []interface{}:
+		ns := make([]interface{}, len(tv))
+		for i := range tv {
+			nv, err := conf.decryptTree(fmt.Sprintf("%s[%d]", k, i), tv[i])
+			if err != nil {
+				return nil, err
+			}
+			ns[i] = nv
+		}
+		return ns, nil
+	case string:
+		if IsEncrypted(tv) {
+			return conf.decrypt(k, tv)
+		}
+	}
+	return v, nil
+}
+
+// redact return a copy of v with the encrypted values replaced by Redacted
+func redact(v interface{}) interface{} {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		nm := make(map[string]interface{}, len(tv))
+		for k, mv := range tv {
+			nm[k] = redact(mv)
+		}
+		return nm
+	case []interface{}:
+		ns := make([]interface{}, len(tv))
+		for i := range tv {
+			ns[i] = redact(tv[i])
+		}
+		return ns
+	case string:
+		if IsEncrypted(tv) {
+			return Redacted
+		}
+	}
+	return v
+}
+
+// redactErrors replace the decrypted values in the TypeError and ParseError of errs,
+// raw is the value of path k before decryptTree
+func redactErrors(k string, raw interface{}, errs []error) {
+	for _, err := range errs {
+		switch e := err.(type) {
+		case *TypeError:
+			if v, ok := redactedAt(k, raw, e.Key); ok {
+				e.Value = v
+			}
+		case *ParseError:
+			if v, ok := redactedAt(k, raw, e.Key); ok {
+				// the parse errors like strconv.NumError quote the value too
+				if s := fmt.Sprint(e.Value); s != "" && e.Err != nil {
+					e.Err = errors.New(strings.Replace(e.Err.Error(), s, Redacted, -1))
+				}
+				e.Value = v
+			}
+		}
+	}
+}
+
+// redactedAt return the redacted value of path key under raw of path k,
+// ok is false if the value has no encrypted values
+func redactedAt(k string, raw interface{}, key string) (interface{}, bool) {
+	if !strings.HasPrefix(key, k) {
+		return nil, false
+	}
+	v, ok := lookupValue(raw, strings.TrimPrefix(key[len(k):], "."))
+	if !ok {
+		return nil, false
+	}
+	rv := redact(v)
+	return rv, !reflect.DeepEqual(rv, v)
+}
+
+// Dump return the config as indented json to log the effective config,
+// the encrypted values are replaced by Redacted
+func (conf *Config) Dump() ([]byte, error) {
+	conf.cLock.RLock()
+	origin := conf.origin
+	conf.cLock.RUnlock()
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(redact(origin)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestConfigSecret(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawKey, err := DecodeKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, err := Encrypt(rawKey, "p@ss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(enc) || strings.Contains(enc, "p@ss") {
+		t.Fatalf("bad encrypted value %q", enc)
+	}
+
+	data := []byte(`{"db": {"user": "root", "password": "` + enc + `"}, "tokens": ["` + enc + `"]}`)
+	os.Setenv("TEST_CONFIG_SECRET_KEY", key)
+	defer os.Unsetenv("TEST_CONFIG_SECRET_KEY")
+	conf, err := ParseFromData(data, WithKeyProvider(EnvKey("TEST_CONFIG_SECRET_KEY")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ { // the second read is cached
+		if s := conf.String("db.password"); s != "p@ss" {
+			t.Fatalf("String = %q", s)
+		}
+	}
+	var db struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+	}
+	if err = conf.Unmarshal("db", &db); err != nil || db.Password != "p@ss" {
+		t.Fatalf("Unmarshal = %+v, %v", db, err)
+	}
+	var bound struct {
+		DB struct {
+			Password string `config:"password"`
+		} `config:"db"`
+		Tokens []string `config:"tokens"`
+	}
+	if err = conf.Bind("", &bound); err != nil || bound.DB.Password != "p@ss" || bound.Tokens[0] != "p@ss" {
+		t.Fatalf("Bind = %+v, %v", bound, err)
+	}
+
+	dump, err := conf.Dump()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(dump), "p@ss") || strings.Contains(string(dump), "ENC[") || !strings.Contains(string(dump), Redacted) {
+		t.Fatalf("Dump not redacted:\n%s", dump)
+	}
+
+	// no key provider or a wrong key
+	conf, err = ParseFromData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conf.StringE("db.password"); err == nil || !strings.Contains(err.Error(), "no key provider") {
+		t.Fatalf("want no key provider error, got %v", err)
+	}
+	other, _ := GenerateKey()
+	conf, _ = ParseFromData(data, WithKeyProvider(KeyProviderFunc(func() ([]byte, error) { return DecodeKey(other) })))
+	if _, err = conf.StringE("db.password"); err == nil {
+		t.Fatal("decrypt with a wrong key should fail")
+	}
+	if err = conf.Unmarshal("db", &db); err == nil {
+		t.Fatal("Unmarshal with a wrong key should fail")
+	}
+}
+
+func TestConfigSecretErrorRedacted(t *testing.T) {
+	key, _ := GenerateKey()
+	rawKey, _ := DecodeKey(key)
+	enc, err := Encrypt(rawKey, "p@ss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := []byte(`{"db": {"password": "` + enc + `"}, "tokens": ["` + enc + `"]}`)
+	conf, err := ParseFromData(data, WithKeyProvider(KeyProviderFunc(func() ([]byte, error) { return rawKey, nil })))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var bound struct {
+		DB struct {
+			Password int `config:"password"`
+		} `config:"db"`
+	}
+	errs := []error{conf.Bind("", &bound)}
+	_, err = conf.IntE("db.password")
+	errs = append(errs, err)
+	_, err = GetE[[]int](conf, "tokens")
+	errs = append(errs, err)
+	_, err = GetE[string](conf, "db")
+	errs = append(errs, err)
+	for i, err := range errs {
+		if err == nil {
+			t.Fatalf("%d: want an error", i)
+		}
+		if msg := err.Error(); strings.Contains(msg, "p@ss") || !strings.Contains(msg, Redacted) {
+			t.Fatalf("%d: the error not redacted: %s", i, msg)
+		}
+	}
+}
+
+func TestConfigSecretReference(t *testing.T) {
+	key, _ := GenerateKey()
+	rawKey, _ := DecodeKey(key)
+	enc, err := Encrypt(rawKey, "p@ss")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kp := WithKeyProvider(KeyProviderFunc(func() ([]byte, error) { return rawKey, nil }))
+
+	// a whole string reference is decrypted when read
+	conf, err := ParseFromData([]byte(`{"pw": "`+enc+`", "db": {"pw": "${pw}"}}`), kp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := conf.String("db.pw"); s != "p@ss" {
+		t.Fatalf(`conf.String("db.pw") = %q`, s)
+	}
+
+	// a part of a string can not be decrypted
+	for _, data := range []string{
+		`{"pw": "` + enc + `", "dsn": "u:${pw}@h"}`,
+		`{"db": {"pw": "` + enc + `"}, "dsn": "u:${db}@h"}`,
+	} {
+		_, err = ParseFromData([]byte(data), kp)
+		if err == nil || !strings.Contains(err.Error(), "encrypted") || strings.Contains(err.Error(), "ENC[") {
+			t.Fatalf("want an encrypted reference error, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/iyidan/goutils/mise"
+)
+
+// Set set the value of path, the missing objects in the path are created,
+// an index equals to the slice length append to the slice.
+// v is normalized through json, e.g. []string become []interface{},
+// so the typed getters work on it the same way as on parsed data.
+// Set notify the OnChange subscribers, a later conf.Reload drop the changes
+func (conf *Config) Set(path string, v interface{}) error {
+	nv, err := normalize(v)
+	if err != nil {
+		return mise.WrapErrorMsg(err, "config.Set("+path+")")
+	}
+	return conf.update(path, func(parent interface{}, seg pathSegment) (interface{}, error) {
+		return setChild(path, parent, seg, nv)
+	})
+}
+
+// Delete delete the value of path, a slice element is removed from the slice
+func (conf *Config) Delete(path string) error {
+	return conf.update(path, func(parent interface{}, seg pathSegment) (interface{}, error) {
+		return deleteChild(path, parent, seg)
+	})
+}
+
+// update copy the containers along path, apply fn on the last one, then swap the origin
+func (conf *Config) update(path string, fn func(parent interface{}, seg pathSegment) (interface{}, error)) error {
+	segs, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+	if len(segs) == 0 {
+		return fmt.Errorf("config: can not update the root")
+	}
+
+	conf.cLock.Lock()
+	old := conf.origin
+	root, err := updatePath(path, old, segs, fn)
+	if err != nil {
+		conf.cLock.Unlock()
+		return err
+	}
+	origin := root.(map[string]interface{})
+	conf.origin = origin
+	conf.resetCache()
+	conf.cLock.Unlock()
+
+	conf.notify(old, origin)
+	return nil
+}
+
+func updatePath(path string, cur interface{}, segs []pathSegment, fn func(parent interface{}, seg pathSegment) (interface{}, error)) (interface{}, error) {
+	if len(segs) == 1 {
+		return fn(cur, segs[0])
+	}
+	seg := segs[0]
+	var child interface{}
+	switch tv := cur.(type) {
+	case map[string]interface{}:
+		if seg.isIdx {
+			return nil, &TypeError{Key: path, Want: "[]interface{}", Value: cur}
+		}
+		var ok bool
+		if child, ok = tv[seg.key]; !ok {
+			// create the missing object
+			child = map[string]interface{}{}
+		}
+	case []interface{}:
+		if !seg.isIdx {
+			return nil, &TypeError{Key: path, Want: "map[string]interface{}", Value: cur}
+		}
+		if seg.index >= len(tv) {
+			return nil, &KeyError{Key: path}
+		}
+		child = tv[seg.index]
+	default:
+		return nil, &TypeError{Key: path, Want: "map[string]interface{} or []interface{}", Value: cur}
+	}
+	nchild, err := updatePath(path, child, segs[1:], fn)
+	if err != nil {
+		return nil, err
+	}
+	return setChild(path, cur, seg, nchild)
+}
+
+// setChild return a copy of parent with the child of seg set to v
+func setChild(path string, parent interface{}, seg pathSegment, v interface{}) (interface{}, error) {
+	switch tv := parent.(type) {
+	case map[string]interface{}:
+		if seg.isIdx {
+			return nil, &TypeError{Key: path, Want: "[]interface{}", Value: parent}
+		}
+		nm := make(map[string]interface{}, len(tv)+1)
+		for k, mv := range tv {
+			nm[k] = mv
+		}
+		nm[seg.key] = v
+		return nm, nil
+	case []interface{}:
+		if !seg.isIdx {
+			return nil, &TypeError{Key: path, Want: "map[string]interface{}", Value: parent}
+		}
+		if seg.index > len(tv) {
+			return nil, &KeyError{Key: path}
+		}
+		ns := make([]interface{}, len(tv), len(tv)+1)
+		copy(ns, tv)
+		if seg.index == len(tv) {
+			ns = append(ns, v)
+		} else {
+			ns[seg.index] = v
+		}
+		return ns, nil
+	}
+	return nil, &TypeError{Key: path, Want: "map[string]interface{} or []interface{}", Value: parent}
+}
+
+// deleteChild return a copy of parent without the child of seg
+func deleteChild(path string, parent interface{}, seg pathSegment) (interface{}, error) {
+	switch tv := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := tv[seg.key]; seg.isIdx || !ok {
+			return nil, &KeyError{Key: path}
+		}
+		nm := make(map[string]interface{}, len(tv))
+		for k, mv := range tv {
+			if k != seg.key {
+				nm[k] = mv
+			}
+		}
+		return nm, nil
+	case []interface{}:
+		if !seg.isIdx || seg.index >= len(tv) {
+			return nil, &KeyError{Key: path}
+		}
+		ns := make([]interface{}, 0, len(tv)-1)
+		ns = append(ns, tv[:seg.index]...)
+		return append(ns, tv[seg.index+1:]...), nil
+	}
+	return nil, &KeyError{Key: path}
+}
+
+// normalize convert v into the types json.Unmarshal produce
+func normalize(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var nv interface{}
+	err = json.Unmarshal(data, &nv)
+	return nv, err
+}
+
+// ErrNotSavable the config data is changed by "@include" or "${...}" when loading, or merged
+// from several Loader layers or env/flag layers, saving it would flatten the included files
+// and layers and write the resolved values (like secrets from env)
+var ErrNotSavable = errors.New("config: resolved from includes, references or layers, can not save")
+
+// SaveToFile write the config into filename as indented json, comments of the
+// original file are not kept. the file is written into a temp file in the same
+// directory then renamed, so readers never see a half written file.
+// a config which used "@include", "${...}", several Loader layers or an env/flag layer return ErrNotSavable
+func (conf *Config) SaveToFile(filename string) error {
+	conf.cLock.RLock()
+	origin, resolved := conf.origin, conf.resolved
+	conf.cLock.RUnlock()
+	if resolved {
+		return ErrNotSavable
+	}
+
+	data, err := json.MarshalIndent(origin, "", "    ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	return mise.WriteFileAtomic(filename, data, 0644)
+}
@@ -0,0 +1,182 @@
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestConfigSetDelete(t *testing.T) {
+	conf, err := ParseFromData([]byte(`{"db": {"pool": {"max": 10}}, "servers": [{"host": "a"}, {"host": "b"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	origin := conf.origin
+
+	var changed []string
+	conf.OnChange("db.pool.max", func(k string, old, new interface{}) {
+		changed = append(changed, k)
+	})
+
+	// warm the cache
+	if conf.Int("db.pool.max") != 10 {
+		t.Fatal(`conf.Int("db.pool.max") != 10`)
+	}
+	if err = conf.Set("db.pool.max", 20); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Int("db.pool.max") != 20 {
+		t.Fatal(`cached conf.Int("db.pool.max") not invalidated`)
+	}
+	if len(changed) != 1 {
+		t.Fatalf("OnChange not notified: %v", changed)
+	}
+	if origin["db"].(map[string]interface{})["pool"].(map[string]interface{})["max"] != float64(10) {
+		t.Fatal("conf.Set should not modify the old data")
+	}
+
+	if err = conf.Set("cache.redis.addrs", []string{"127.0.0.1:6379"}); err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.SliceString("cache.redis.addrs"); !reflect.DeepEqual(v, []string{"127.0.0.1:6379"}) {
+		t.Fatalf(`conf.SliceString("cache.redis.addrs") = %v`, v)
+	}
+	if err = conf.Set("servers[2]", map[string]string{"host": "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Set("servers[0].host", "a1"); err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.String("servers[2].host"); v != "c" {
+		t.Fatalf(`conf.String("servers[2].host") = %s`, v)
+	}
+
+	if err = conf.Delete("servers[1]"); err != nil {
+		t.Fatal(err)
+	}
+	if v := conf.String("servers[1].host"); v != "c" {
+		t.Fatalf(`after delete conf.String("servers[1].host") = %s`, v)
+	}
+	if err = conf.Delete("db.pool"); err != nil {
+		t.Fatal(err)
+	}
+	if conf.Get("db.pool.max") != nil {
+		t.Fatal(`"db.pool" not deleted`)
+	}
+
+	for _, path := range []string{"db.pool", "servers[5]", "not.exists"} {
+		if err = conf.Delete(path); !IsNotExist(err) {
+			t.Fatalf("conf.Delete(%s) want *KeyError, got: %v", path, err)
+		}
+	}
+	if err = conf.Set("servers[5].host", "x"); !IsNotExist(err) {
+		t.Fatalf("conf.Set(servers[5].host) want *KeyError, got: %v", err)
+	}
+	if err = conf.Set("servers[0].host.name", "x"); err == nil {
+		t.Fatal("set through a string should fail")
+	}
+	if err = conf.Set("", 1); err == nil {
+		t.Fatal("set the root should fail")
+	}
+}
+
+func TestConfigSaveToFile(t *testing.T) {
+	tmpfile, err := getTempfileWithJSON([]byte(`{"name": "a", /* comment */ "db": {"max": 1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmpfile)
+	if err = os.Chmod(tmpfile, 0640); err != nil {
+		t.Fatal(err)
+	}
+
+	conf, err := ParseFromFile(tmpfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.Set("db.max", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.SaveToFile(tmpfile); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(tmpfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm() != 0640 {
+		t.Fatalf("file mode changed: %s", fi.Mode())
+	}
+
+	saved, err := ParseFromFile(tmpfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Int("db.max") != 2 || saved.String("name") != "a" {
+		t.Fatalf("saved config: %#v", saved.origin)
+	}
+
+	// the includes and references are not flattened into the file
+	os.Setenv("TEST_CONFIG_SAVE_PASSWORD", "p@ss")
+	defer os.Unsetenv("TEST_CONFIG_SAVE_PASSWORD")
+	for _, data := range []string{
+		`{"db": {"password": "${env:TEST_CONFIG_SAVE_PASSWORD}"}}`,
+		`{"@include": "` + filepath.Base(tmpfile) + `", "name": "b"}`,
+	} {
+		mainfile := filepath.Join(filepath.Dir(tmpfile), "main-"+filepath.Base(tmpfile))
+		if err = ioutil.WriteFile(mainfile, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Remove(mainfile)
+		if conf, err = ParseFromFile(mainfile); err != nil {
+			t.Fatal(err)
+		}
+		if err = conf.SaveToFile(mainfile); err != ErrNotSavable {
+			t.Fatalf("want ErrNotSavable, got %v", err)
+		}
+		if saved, _ := ioutil.ReadFile(mainfile); string(saved) != data {
+			t.Fatalf("the file changed: %s", saved)
+		}
+		// neither the sub configs
+		if err = conf.Sub("").SaveToFile(mainfile); err != ErrNotSavable {
+			t.Fatalf("Sub: want ErrNotSavable, got %v", err)
+		}
+	}
+
+	// the layers are not flattened into the file, the env values not persisted
+	os.Setenv("TEST_CONFIG_SAVE_B", "secretenv")
+	defer os.Unsetenv("TEST_CONFIG_SAVE_B")
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Int("a", 0, "")
+	if err = fs.Parse([]string{"-a=2"}); err != nil {
+		t.Fatal(err)
+	}
+	for i, l := range []*Loader{
+		NewLoader().AddData("a.json", []byte(`{"a": 1}`)).AddEnv("TEST_CONFIG_SAVE_"),
+		NewLoader().AddEnv("TEST_CONFIG_SAVE_"),
+		NewLoader().AddFlags(fs),
+		NewLoader().AddFile(tmpfile).AddData("b.json", []byte(`{"b": 1}`)),
+	} {
+		if conf, err = l.Load(); err != nil {
+			t.Fatal(err)
+		}
+		if err = conf.SaveToFile(tmpfile); err != ErrNotSavable {
+			t.Fatalf("%d: want ErrNotSavable, got %v", i, err)
+		}
+	}
+	if saved, _ := ioutil.ReadFile(tmpfile); strings.Contains(string(saved), "secretenv") {
+		t.Fatalf("the env value persisted: %s", saved)
+	}
+	// a single file layer is savable
+	if conf, err = NewLoader().AddFile(tmpfile).Load(); err != nil {
+		t.Fatal(err)
+	}
+	if err = conf.SaveToFile(tmpfile); err != nil {
+		t.Fatal(err)
+	}
+}
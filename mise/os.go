@@ -1,6 +1,7 @@
 package mise
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -30,3 +31,32 @@ func GetRootPath() string {
 	}
 	return p
 }
+
+// WriteFileAtomic write data to a temp file in the dir of filename, then rename it to filename,
+// so filename is either the old or the new content on failures. the mode of an existing
+// filename is kept, perm is used for a new one
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	if fi, err := os.Stat(filename); err == nil {
+		perm = fi.Mode().Perm()
+	}
+
+	tmpfile, err := ioutil.TempFile(filepath.Dir(filename), "."+filepath.Base(filename)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err = tmpfile.Write(data); err == nil {
+		err = tmpfile.Sync()
+	}
+	if cerr := tmpfile.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Chmod(tmpfile.Name(), perm)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmpfile.Name(), filename)
+}
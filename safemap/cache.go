@@ -0,0 +1,272 @@
+package safemap
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+	"sync/atomic"
+)
+
+// Policy is the eviction policy of a Cache
+type Policy int
+
+// eviction policies
+const (
+	LRU Policy = iota // evict the least recently used entry
+	LFU               // evict the least frequently used entry, the least recently used one of the ties
+)
+
+func (p Policy) String() string {
+	switch p {
+	case LRU:
+		return "lru"
+	case LFU:
+		return "lfu"
+	}
+	return "unknown"
+}
+
+// CacheStats is the counters of a Cache
+type CacheStats struct {
+	Hits      uint64 // Get and GetCheck of the cached keys
+	Misses    uint64 // Get and GetCheck of the not cached keys
+	Evictions uint64 // entries evicted to free the capacity
+}
+
+type cacheEntry[K comparable, V any] struct {
+	k    K
+	v    V
+	cost int64
+
+	elem  *list.Element // of LRU
+	freq  uint64        // of LFU
+	tick  uint64        // of LFU, the last use
+	index int           // of LFU, the index in the heap
+}
+
+// Cache is a capacity bounded concurrency map, the entries are evicted by
+// the policy when the total cost exceed the capacity
+type Cache[K comparable, V any] struct {
+	l        sync.Mutex
+	m        map[K]*cacheEntry[K, V]
+	policy   Policy
+	capacity int64
+	total    int64
+	cost     func(k K, v V) int64
+
+	lru  *list.List    // front is the most recently used
+	lfu  lfuHeap[K, V] // top is the next to evict
+	tick uint64
+
+	hits, misses, evictions uint64
+}
+
+// NewCache return an inited cache evicting by policy, capacity is the max total cost,
+// cost return the cost of an entry, like its size in bytes, every entry cost 1 if nil
+func NewCache[K comparable, V any](capacity int64, policy Policy, cost func(k K, v V) int64) *Cache[K, V] {
+	if cost == nil {
+		cost = func(K, V) int64 { return 1 }
+	}
+	return &Cache[K, V]{
+		m:        make(map[K]*cacheEntry[K, V]),
+		policy:   policy,
+		capacity: capacity,
+		cost:     cost,
+		lru:      list.New(),
+	}
+}
+
+// touch record a use of e, must hold the lock
+func (c *Cache[K, V]) touch(e *cacheEntry[K, V]) {
+	if c.policy == LRU {
+		c.lru.MoveToFront(e.elem)
+		return
+	}
+	c.tick++
+	e.freq, e.tick = e.freq+1, c.tick
+	heap.Fix(&c.lfu, e.index)
+}
+
+// insert add e to the map and the policy, must hold the lock
+func (c *Cache[K, V]) insert(e *cacheEntry[K, V]) {
+	c.m[e.k] = e
+	c.total += e.cost
+	if c.policy == LRU {
+		e.elem = c.lru.PushFront(e)
+		return
+	}
+	c.tick++
+	e.tick = c.tick
+	heap.Push(&c.lfu, e)
+}
+
+// remove delete e from the map and the policy, must hold the lock
+func (c *Cache[K, V]) remove(e *cacheEntry[K, V]) {
+	delete(c.m, e.k)
+	c.total -= e.cost
+	if c.policy == LRU {
+		c.lru.Remove(e.elem)
+		return
+	}
+	heap.Remove(&c.lfu, e.index)
+}
+
+// victim return the next entry to evict, must hold the lock
+func (c *Cache[K, V]) victim() *cacheEntry[K, V] {
+	if c.policy == LRU {
+		return c.lru.Back().Value.(*cacheEntry[K, V])
+	}
+	return c.lfu[0]
+}
+
+// store set k with v, evict the others to make room, must hold the lock.
+// return false if the entry alone exceed the capacity, it is not cached
+func (c *Cache[K, V]) store(k K, v V) bool {
+	var freq uint64
+	if old, ok := c.m[k]; ok {
+		c.remove(old)
+		freq = old.freq
+	}
+	e := &cacheEntry[K, V]{k: k, v: v, cost: c.cost(k, v), freq: freq}
+	if e.cost > c.capacity {
+		return false
+	}
+	for c.total+e.cost > c.capacity {
+		c.remove(c.victim())
+		atomic.AddUint64(&c.evictions, 1)
+	}
+	c.insert(e)
+	return true
+}
+
+// Add if k already in the cache, return false, also return false if the entry
+// cost more than the capacity
+func (c *Cache[K, V]) Add(k K, v V) bool {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if _, ok := c.m[k]; ok {
+		return false
+	}
+	return c.store(k, v)
+}
+
+// Set set key k with value v, an entry cost more than the capacity is not cached
+func (c *Cache[K, V]) Set(k K, v V) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.store(k, v)
+}
+
+// Get get value of the given k, if k not cached, return the zero value
+func (c *Cache[K, V]) Get(k K) V {
+	v, _ := c.GetCheck(k)
+	return v
+}
+
+// GetCheck get value of the given k and mark it used.
+// if k not cached, ok is false
+func (c *Cache[K, V]) GetCheck(k K) (V, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	e, ok := c.m[k]
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		var zero V
+		return zero, false
+	}
+	atomic.AddUint64(&c.hits, 1)
+	c.touch(e)
+	return e.v, true
+}
+
+// Peek same as GetCheck, but not mark k used and not count a hit or miss
+func (c *Cache[K, V]) Peek(k K) (V, bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if e, ok := c.m[k]; ok {
+		return e.v, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Exist check given k is cached, not mark k used
+func (c *Cache[K, V]) Exist(k K) bool {
+	_, ok := c.Peek(k)
+	return ok
+}
+
+// Del del the given key
+func (c *Cache[K, V]) Del(k K) {
+	c.l.Lock()
+	defer c.l.Unlock()
+	if e, ok := c.m[k]; ok {
+		c.remove(e)
+	}
+}
+
+// DelAll delete all keys, the counters are kept
+func (c *Cache[K, V]) DelAll() {
+	c.l.Lock()
+	defer c.l.Unlock()
+	c.m = make(map[K]*cacheEntry[K, V])
+	c.total = 0
+	c.lru.Init()
+	c.lfu = nil
+}
+
+// Len get the cached keys count
+func (c *Cache[K, V]) Len() int {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return len(c.m)
+}
+
+// Cost get the total cost of the cached entries
+func (c *Cache[K, V]) Cost() int64 {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return c.total
+}
+
+// Stats get the counters
+func (c *Cache[K, V]) Stats() CacheStats {
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+	}
+}
+
+// lfuHeap order the entries by frequency then by the last use
+type lfuHeap[K comparable, V any] []*cacheEntry[K, V]
+
+func (h lfuHeap[K, V]) Len() int {
+	return len(h)
+}
+
+func (h lfuHeap[K, V]) Less(i, j int) bool {
+	if h[i].freq != h[j].freq {
+		return h[i].freq < h[j].freq
+	}
+	return h[i].tick < h[j].tick
+}
+
+func (h lfuHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+func (h *lfuHeap[K, V]) Push(x interface{}) {
+	e := x.(*cacheEntry[K, V])
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *lfuHeap[K, V]) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+	return e
+}
@@ -0,0 +1,135 @@
+package safemap
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestCacheLRU(t *testing.T) {
+	c := NewCache[string, int](3, LRU, nil)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a") // b is the least recently used now
+	c.Set("d", 4)
+	if c.Exist("b") || !c.Exist("a") || c.Len() != 3 {
+		t.Fatal("b should be evicted")
+	}
+
+	// Peek not update the recency, c is evicted next
+	if v, ok := c.Peek("c"); !ok || v != 3 {
+		t.Fatalf(`c.Peek("c") = %d, %v`, v, ok)
+	}
+	if !c.Add("e", 5) || c.Add("e", 6) {
+		t.Fatal("Add failed")
+	}
+	if c.Exist("c") {
+		t.Fatal("c should be evicted")
+	}
+
+	// Set of a cached key make it the most recently used
+	c.Set("a", 10)
+	c.Set("f", 6)
+	c.Set("g", 7)
+	if v, ok := c.GetCheck("a"); !ok || v != 10 {
+		t.Fatalf(`c.GetCheck("a") = %d, %v`, v, ok)
+	}
+	if _, ok := c.GetCheck("d"); ok {
+		t.Fatal("d should be evicted")
+	}
+
+	stats := c.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 || stats.Evictions != 4 {
+		t.Fatalf("c.Stats() = %+v", stats)
+	}
+
+	c.Del("a")
+	if c.Len() != 2 || c.Cost() != 2 {
+		t.Fatalf("c.Len() = %d, c.Cost() = %d", c.Len(), c.Cost())
+	}
+	c.DelAll()
+	if c.Len() != 0 || c.Cost() != 0 {
+		t.Fatal("DelAll failed")
+	}
+	c.Set("x", 1)
+	if c.Get("x") != 1 {
+		t.Fatal("Set after DelAll failed")
+	}
+}
+
+func TestCacheLFU(t *testing.T) {
+	c := NewCache[string, int](3, LFU, nil)
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+	c.Get("a")
+	c.Get("a")
+	c.Get("b")
+	c.Get("c")
+	c.Get("c")
+	// b is the least frequently used
+	c.Set("d", 4)
+	if c.Exist("b") || !c.Exist("a") || !c.Exist("c") {
+		t.Fatal("b should be evicted")
+	}
+	// d is used least, evicted before the others
+	c.Set("e", 5)
+	if c.Exist("d") || !c.Exist("e") {
+		t.Fatal("d should be evicted")
+	}
+	// the ties are evicted by the least recently used
+	c.Get("e")
+	c.Get("e")
+	c.Get("a")
+	c.Set("f", 6)
+	if c.Exist("c") {
+		t.Fatal("c should be evicted")
+	}
+	if stats := c.Stats(); stats.Evictions != 3 {
+		t.Fatalf("c.Stats() = %+v", stats)
+	}
+}
+
+func TestCacheCost(t *testing.T) {
+	c := NewCache[string, []byte](10, LRU, func(k string, v []byte) int64 {
+		return int64(len(v))
+	})
+	c.Set("a", make([]byte, 4))
+	c.Set("b", make([]byte, 4))
+	if c.Cost() != 8 {
+		t.Fatalf("c.Cost() = %d", c.Cost())
+	}
+	c.Set("c", make([]byte, 6))
+	if c.Exist("a") || !c.Exist("b") || c.Cost() != 10 {
+		t.Fatalf("a should be evicted, c.Cost() = %d", c.Cost())
+	}
+	if c.Add("big", make([]byte, 11)) || c.Exist("big") || !c.Exist("c") {
+		t.Fatal("an entry cost more than the capacity should not be cached")
+	}
+	c.Set("c", make([]byte, 2))
+	if c.Cost() != 6 || c.Len() != 2 {
+		t.Fatalf("c.Cost() = %d after update", c.Cost())
+	}
+}
+
+func TestCacheConcurrent(t *testing.T) {
+	for _, policy := range []Policy{LRU, LFU} {
+		c := NewCache[int, int](64, policy, nil)
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					k := (g*1000 + i) % 100
+					c.Set(k, i)
+					c.Get(k)
+				}
+			}(g)
+		}
+		wg.Wait()
+		if c.Len() > 64 {
+			t.Fatalf("%s: c.Len() = %d exceed the capacity", policy, c.Len())
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package safemap
+
+import "sync"
+
+// call is a running factory of GetOrCompute, the other callers of the key wait it
+type call[V any] struct {
+	wg sync.WaitGroup
+	v  V
+	ok bool // false if the factory panicked
+}
+
+// Compute set k to the value returned by f atomically, f get the current value
+// and whether k exists, k is deleted if keep is false. return the new value and keep.
+// f is called with the lock held, it must not use the map
+func (m *Map[K, V]) Compute(k K, f func(old V, exists bool) (nv V, keep bool)) (V, bool) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	old, ok := m.m[k]
+	nv, keep := f(old, ok)
+	if keep {
+		m.m[k] = nv
+	} else {
+		delete(m.m, k)
+	}
+	return nv, keep
+}
+
+// GetOrCompute get the value of k, if k not exists, set it to the value returned by factory.
+// the factory of a key runs once for the concurrent callers, the others wait and get its value,
+// it runs without the lock held, so it can be slow or use the other keys of the map,
+// but it must not call GetOrCompute of the same key, which wait the factory itself forever.
+// a value of k set by the factory wins over the returned one, like Add.
+// loaded is true if the value is not computed by this call
+func (m *Map[K, V]) GetOrCompute(k K, factory func() V) (v V, loaded bool) {
+	for {
+		m.l.RLock()
+		v, ok := m.m[k]
+		m.l.RUnlock()
+		if ok {
+			return v, true
+		}
+
+		m.l.Lock()
+		if v, ok := m.m[k]; ok {
+			m.l.Unlock()
+			return v, true
+		}
+		if c, ok := m.calls[k]; ok {
+			m.l.Unlock()
+			c.wg.Wait()
+			if c.ok {
+				return c.v, true
+			}
+			// the factory panicked, try it again
+			continue
+		}
+		c := &call[V]{}
+		c.wg.Add(1)
+		if m.calls == nil {
+			m.calls = make(map[K]*call[V])
+		}
+		m.calls[k] = c
+		m.l.Unlock()
+		return m.runFactory(k, c, factory)
+	}
+}
+
+func (m *Map[K, V]) runFactory(k K, c *call[V], factory func() V) (v V, loaded bool) {
+	defer func() {
+		m.l.Lock()
+		delete(m.calls, k)
+		if c.ok {
+			// a value set while the factory ran wins, like Add
+			if ov, ok := m.m[k]; ok {
+				c.v, v, loaded = ov, ov, true
+			} else {
+				m.m[k] = c.v
+			}
+		}
+		m.l.Unlock()
+		c.wg.Done()
+	}()
+	c.v = factory()
+	c.ok = true
+	return c.v, false
+}
+
+// Update set k to the value returned by f if k exists, f get the current value.
+// return the new value and whether k exists. f is called with the lock held, it must not use the map
+func (m *Map[K, V]) Update(k K, f func(old V) V) (V, bool) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	old, ok := m.m[k]
+	if !ok {
+		return old, false
+	}
+	nv := f(old)
+	m.m[k] = nv
+	return nv, true
+}
+
+// Swap set k with v, return the previous value and whether k existed
+func (m *Map[K, V]) Swap(k K, v V) (old V, loaded bool) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	old, loaded = m.m[k]
+	m.m[k] = v
+	return
+}
+
+// LoadAndDelete del the given key, return the value and whether k existed
+func (m *Map[K, V]) LoadAndDelete(k K) (V, bool) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	v, ok := m.m[k]
+	delete(m.m, k)
+	return v, ok
+}
+
+// CasSetFunc same as CasSet, but compare the values by eq,
+// for the values not comparable by ==, like slices and maps
+func (m *Map[K, V]) CasSetFunc(k K, v V, lastv V, eq func(a, b V) bool) bool {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if tmpv, ok := m.m[k]; !ok || eq(tmpv, lastv) {
+		m.m[k] = v
+		return true
+	}
+	return false
+}
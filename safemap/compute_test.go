@@ -0,0 +1,151 @@
+package safemap
+
+import (
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMapCompute(t *testing.T) {
+	m := NewMap[string, []string]()
+	appendf := func(s string) func(old []string, exists bool) ([]string, bool) {
+		return func(old []string, exists bool) ([]string, bool) {
+			return append(old, s), true
+		}
+	}
+	m.Compute("k", appendf("a"))
+	if v, keep := m.Compute("k", appendf("b")); !keep || !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Fatalf(`m.Compute("k") = %v, %v`, v, keep)
+	}
+	if _, keep := m.Compute("k", func(old []string, exists bool) ([]string, bool) {
+		return nil, false
+	}); keep || m.Exist("k") {
+		t.Fatal("Compute should delete k if not keep")
+	}
+
+	// the counters without retry loops
+	counters := NewMap[string, int]()
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				counters.Compute("n", func(old int, exists bool) (int, bool) {
+					return old + 1, true
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if v := counters.Get("n"); v != 8000 {
+		t.Fatalf(`counters.Get("n") = %d`, v)
+	}
+}
+
+func TestMapGetOrCompute(t *testing.T) {
+	m := NewMap[string, int]()
+	var runs int32
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	results := make([]int, 16)
+	computed := int32(0)
+	for g := range results {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			<-start
+			v, loaded := m.GetOrCompute("k", func() int {
+				atomic.AddInt32(&runs, 1)
+				time.Sleep(10 * time.Millisecond)
+				return 42
+			})
+			if !loaded {
+				atomic.AddInt32(&computed, 1)
+			}
+			results[g] = v
+		}(g)
+	}
+	close(start)
+	wg.Wait()
+	if runs != 1 || computed != 1 {
+		t.Fatalf("the factory ran %d times, %d computed", runs, computed)
+	}
+	for g, v := range results {
+		if v != 42 {
+			t.Fatalf("caller %d got %d", g, v)
+		}
+	}
+	if v, loaded := m.GetOrCompute("k", func() int { return 0 }); !loaded || v != 42 {
+		t.Fatalf(`m.GetOrCompute("k") = %d, %v`, v, loaded)
+	}
+
+	// the factory can use the other keys of the map
+	if v, _ := m.GetOrCompute("k2", func() int { return m.Get("k") + 1 }); v != 43 {
+		t.Fatalf(`m.GetOrCompute("k2") = %d`, v)
+	}
+
+	// a panicking factory not block the next callers
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("the factory panic not propagated")
+			}
+		}()
+		m.GetOrCompute("p", func() int { panic("boom") })
+	}()
+	if v, loaded := m.GetOrCompute("p", func() int { return 1 }); loaded || v != 1 {
+		t.Fatalf(`m.GetOrCompute("p") = %d, %v after a panic`, v, loaded)
+	}
+}
+
+func TestMapUpdateSwapLoadAndDelete(t *testing.T) {
+	m := NewMap[string, int]()
+	if _, ok := m.Update("k", func(old int) int { return old + 1 }); ok || m.Exist("k") {
+		t.Fatal("Update should not set a not exists key")
+	}
+	m.Set("k", 1)
+	if v, ok := m.Update("k", func(old int) int { return old + 1 }); !ok || v != 2 {
+		t.Fatalf(`m.Update("k") = %d, %v`, v, ok)
+	}
+
+	if old, loaded := m.Swap("k", 10); !loaded || old != 2 || m.Get("k") != 10 {
+		t.Fatalf(`m.Swap("k") = %d, %v`, old, loaded)
+	}
+	if old, loaded := m.Swap("new", 1); loaded || old != 0 {
+		t.Fatalf(`m.Swap("new") = %d, %v`, old, loaded)
+	}
+
+	if v, ok := m.LoadAndDelete("k"); !ok || v != 10 || m.Exist("k") {
+		t.Fatalf(`m.LoadAndDelete("k") = %d, %v`, v, ok)
+	}
+	if _, ok := m.LoadAndDelete("k"); ok {
+		t.Fatal(`m.LoadAndDelete("k") of a deleted key`)
+	}
+}
+
+func TestSafeMapCasSetFunc(t *testing.T) {
+	m := New()
+	m.Set("list", []int{1})
+	eq := func(a, b interface{}) bool {
+		return reflect.DeepEqual(a, b)
+	}
+	if !m.CasSetFunc("list", []int{1, 2}, []int{1}, eq) {
+		t.Fatal("CasSetFunc failed")
+	}
+	if m.CasSetFunc("list", []int{3}, []int{1}, eq) {
+		t.Fatal("CasSetFunc with a stale value should fail")
+	}
+	if !reflect.DeepEqual(m.Get("list"), []int{1, 2}) {
+		t.Fatalf(`m.Get("list") = %v`, m.Get("list"))
+	}
+
+	s := NewSharded[string, map[string]int](4, nil)
+	if !s.CasSetFunc("m", map[string]int{"a": 1}, nil, func(a, b map[string]int) bool {
+		return reflect.DeepEqual(a, b)
+	}) {
+		t.Fatal("Sharded.CasSetFunc of a new key failed")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build go1.24
+
+package safemap
+
+import "hash/maphash"
+
+// defaultHash return maphash.Comparable with a random seed
+func defaultHash[K comparable]() func(K) uint64 {
+	seed := maphash.MakeSeed()
+	return func(k K) uint64 {
+		return maphash.Comparable(seed, k)
+	}
+}
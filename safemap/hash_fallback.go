@@ -0,0 +1,74 @@
+//go:build !go1.24
+
+package safemap
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// defaultHash return a reflect based hash with a random seed, maphash.Comparable is go1.24+.
+// the equal keys have the same hash, e.g. +0 and -0, the interfaces of the same dynamic value
+func defaultHash[K comparable]() func(K) uint64 {
+	seed := maphash.MakeSeed()
+	return func(k K) uint64 {
+		var h maphash.Hash
+		h.SetSeed(seed)
+		writeHash(&h, reflect.ValueOf(&k).Elem())
+		return h.Sum64()
+	}
+}
+
+func writeHash(h *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
+	writeUint := func(n uint64) {
+		binary.LittleEndian.PutUint64(buf[:], n)
+		h.Write(buf[:])
+	}
+	writeFloat := func(f float64) {
+		if f == 0 {
+			f = 0 // -0 equals +0
+		}
+		writeUint(math.Float64bits(f))
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			writeUint(1)
+		} else {
+			writeUint(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		writeUint(uint64(v.Int()))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		writeUint(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		writeFloat(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		writeFloat(real(c))
+		writeFloat(imag(c))
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		writeUint(uint64(v.Pointer()))
+	case reflect.Interface:
+		if v.IsNil() {
+			writeUint(0)
+			return
+		}
+		h.WriteString(v.Elem().Type().String())
+		writeHash(h, v.Elem())
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			writeHash(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			writeHash(h, v.Field(i))
+		}
+	}
+}
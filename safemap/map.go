@@ -0,0 +1,131 @@
+package safemap
+
+import "sync"
+
+// Map is a type-safe concurrency map
+type Map[K comparable, V any] struct {
+	l sync.RWMutex
+	m map[K]V
+
+	calls map[K]*call[V] // the running factories of GetOrCompute, guarded by l
+}
+
+// NewMap return an inited type-safe concurrency map
+func NewMap[K comparable, V any]() *Map[K, V] {
+	return &Map[K, V]{m: make(map[K]V)}
+}
+
+// equal compare the values like ==, it panics if the values are not comparable
+func equal[V any](a, b V) bool {
+	return any(a) == any(b)
+}
+
+// Add if k already in the map, return false
+func (m *Map[K, V]) Add(k K, v V) bool {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if _, ok := m.m[k]; !ok {
+		m.m[k] = v
+	} else {
+		return false
+	}
+	return true
+}
+
+// Set set key k with value v
+func (m *Map[K, V]) Set(k K, v V) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	m.m[k] = v
+}
+
+// CasSet compare and set v, the values are compared by ==,
+// it panics if they are not comparable, like slices and maps, use CasSetFunc for them
+func (m *Map[K, V]) CasSet(k K, v V, lastv V) bool {
+	m.l.Lock()
+	defer m.l.Unlock()
+	if tmpv, ok := m.m[k]; !ok || equal(tmpv, lastv) {
+		m.m[k] = v
+		return true
+	}
+	return false
+}
+
+// CasMultiSet compare and update multiple
+func (m *Map[K, V]) CasMultiSet(update, old map[K]V) bool {
+	m.l.Lock()
+	defer m.l.Unlock()
+
+	// old value compare
+	for k, v := range old {
+		if tmpv, ok := m.m[k]; !ok || !equal(tmpv, v) {
+			return false
+		}
+	}
+	// new value set
+	for k, v := range update {
+		m.m[k] = v
+	}
+	return true
+}
+
+// Get get value of the given k, if k not exists, return the zero value
+func (m *Map[K, V]) Get(k K) V {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	return m.m[k]
+}
+
+// GetCheck get value of the given k, if k exists.
+// if k not exists, ok is false
+func (m *Map[K, V]) GetCheck(k K) (V, bool) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	val, ok := m.m[k]
+	return val, ok
+}
+
+// GetAll get a copy of m.m
+func (m *Map[K, V]) GetAll() (cmap map[K]V) {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	cmap = make(map[K]V)
+
+	for k, v := range m.m {
+		cmap[k] = v
+	}
+	return
+}
+
+// Exist check given k is exists
+func (m *Map[K, V]) Exist(k K) bool {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	if _, ok := m.m[k]; ok {
+		return true
+	}
+	return false
+}
+
+// Del del the given key
+func (m *Map[K, V]) Del(k K) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	delete(m.m, k)
+}
+
+// DelAll delete all keys
+func (m *Map[K, V]) DelAll() {
+	m.l.Lock()
+	defer m.l.Unlock()
+	for k := range m.m {
+		delete(m.m, k)
+	}
+}
+
+// Len get the map keys count
+func (m *Map[K, V]) Len() int {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	return len(m.m)
+}
@@ -0,0 +1,98 @@
+package safemap
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestMap(t *testing.T) {
+	m := NewMap[string, int]()
+
+	if !m.Add("a", 1) || m.Add("a", 2) {
+		t.Fatal("Add failed")
+	}
+	m.Set("b", 2)
+	if v := m.Get("a"); v != 1 {
+		t.Fatalf(`m.Get("a") = %d`, v)
+	}
+	if v := m.Get("nope"); v != 0 {
+		t.Fatalf(`m.Get("nope") = %d`, v)
+	}
+	if v, ok := m.GetCheck("b"); !ok || v != 2 {
+		t.Fatalf(`m.GetCheck("b") = %d, %v`, v, ok)
+	}
+	if _, ok := m.GetCheck("nope"); ok {
+		t.Fatal(`m.GetCheck("nope") ok`)
+	}
+	if !m.Exist("a") || m.Exist("nope") {
+		t.Fatal("Exist failed")
+	}
+
+	if !m.CasSet("a", 3, 1) || m.CasSet("a", 4, 1) || m.Get("a") != 3 {
+		t.Fatal("CasSet failed")
+	}
+	if !m.CasSet("c", 5, 0) || m.Get("c") != 5 {
+		t.Fatal("CasSet of a new key failed")
+	}
+	if !m.CasMultiSet(map[string]int{"a": 10, "b": 20}, map[string]int{"a": 3, "b": 2}) {
+		t.Fatal("CasMultiSet failed")
+	}
+	if m.CasMultiSet(map[string]int{"a": 30}, map[string]int{"a": 3}) {
+		t.Fatal("CasMultiSet with stale values should fail")
+	}
+
+	all := m.GetAll()
+	if !reflect.DeepEqual(all, map[string]int{"a": 10, "b": 20, "c": 5}) {
+		t.Fatalf("m.GetAll() = %v", all)
+	}
+	all["a"] = 100
+	if m.Get("a") != 10 {
+		t.Fatal("GetAll is not a copy")
+	}
+
+	m.Del("a")
+	if m.Len() != 2 {
+		t.Fatalf("m.Len() = %d", m.Len())
+	}
+	m.DelAll()
+	if m.Len() != 0 {
+		t.Fatalf("m.Len() = %d after DelAll", m.Len())
+	}
+}
+
+func TestMapPointerValues(t *testing.T) {
+	type conn struct{ id int }
+	m := NewMap[int, *conn]()
+	c1, c2 := &conn{1}, &conn{1}
+	m.Set(1, c1)
+	if m.CasSet(1, c2, &conn{1}) {
+		t.Fatal("CasSet should compare the pointers")
+	}
+	if !m.CasSet(1, c2, c1) || m.Get(1) != c2 {
+		t.Fatal("CasSet failed")
+	}
+	if m.Get(2) != nil {
+		t.Fatal("m.Get(2) should be nil")
+	}
+}
+
+func TestMapConcurrent(t *testing.T) {
+	m := NewMap[int, int]()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			m.Set(i, i)
+			if v, ok := m.GetCheck(i); !ok || v != i {
+				t.Errorf("m.GetCheck(%d) = %d, %v", i, v, ok)
+			}
+			m.Del(i)
+		}(i)
+	}
+	wg.Wait()
+	if m.Len() != 0 {
+		t.Fatalf("m.Len() = %d", m.Len())
+	}
+}
@@ -0,0 +1,172 @@
+package safemap
+
+import "sort"
+
+// DefaultShards is the shard count of NewSharded if shards <= 0
+const DefaultShards = 32
+
+// Sharded is a concurrency map split into shards by the key hash,
+// each shard has its own lock, so the writers of different shards not block each other.
+// it has the same methods as Map
+type Sharded[K comparable, V any] struct {
+	shards []*Map[K, V]
+	hash   func(K) uint64
+}
+
+// NewSharded return an inited sharded map of the given shard count,
+// hash is the hash function of the keys, maphash.Comparable with a random seed if nil,
+// a reflect based one before go1.24
+func NewSharded[K comparable, V any](shards int, hash func(K) uint64) *Sharded[K, V] {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+	if hash == nil {
+		hash = defaultHash[K]()
+	}
+	s := &Sharded[K, V]{shards: make([]*Map[K, V], shards), hash: hash}
+	for i := range s.shards {
+		s.shards[i] = NewMap[K, V]()
+	}
+	return s
+}
+
+func (s *Sharded[K, V]) index(k K) int {
+	return int(s.hash(k) % uint64(len(s.shards)))
+}
+
+func (s *Sharded[K, V]) shard(k K) *Map[K, V] {
+	return s.shards[s.index(k)]
+}
+
+// Add if k already in the map, return false
+func (s *Sharded[K, V]) Add(k K, v V) bool {
+	return s.shard(k).Add(k, v)
+}
+
+// Set set key k with value v
+func (s *Sharded[K, V]) Set(k K, v V) {
+	s.shard(k).Set(k, v)
+}
+
+// CasSet compare and set v
+func (s *Sharded[K, V]) CasSet(k K, v V, lastv V) bool {
+	return s.shard(k).CasSet(k, v, lastv)
+}
+
+// CasMultiSet compare and update multiple, atomically across the shards:
+// the shards of all the keys are locked in the ascending order of index, so the
+// concurrent calls not deadlock
+func (s *Sharded[K, V]) CasMultiSet(update, old map[K]V) bool {
+	seen := make(map[int]bool)
+	var idx []int
+	for _, m := range []map[K]V{old, update} {
+		for k := range m {
+			if i := s.index(k); !seen[i] {
+				seen[i] = true
+				idx = append(idx, i)
+			}
+		}
+	}
+	sort.Ints(idx)
+	for _, i := range idx {
+		s.shards[i].l.Lock()
+	}
+	defer func() {
+		for j := len(idx) - 1; j >= 0; j-- {
+			s.shards[idx[j]].l.Unlock()
+		}
+	}()
+
+	// old value compare
+	for k, v := range old {
+		if tmpv, ok := s.shard(k).m[k]; !ok || !equal(tmpv, v) {
+			return false
+		}
+	}
+	// new value set
+	for k, v := range update {
+		s.shard(k).m[k] = v
+	}
+	return true
+}
+
+// Compute same as Map.Compute
+func (s *Sharded[K, V]) Compute(k K, f func(old V, exists bool) (nv V, keep bool)) (V, bool) {
+	return s.shard(k).Compute(k, f)
+}
+
+// GetOrCompute same as Map.GetOrCompute
+func (s *Sharded[K, V]) GetOrCompute(k K, factory func() V) (v V, loaded bool) {
+	return s.shard(k).GetOrCompute(k, factory)
+}
+
+// Update same as Map.Update
+func (s *Sharded[K, V]) Update(k K, f func(old V) V) (V, bool) {
+	return s.shard(k).Update(k, f)
+}
+
+// Swap same as Map.Swap
+func (s *Sharded[K, V]) Swap(k K, v V) (old V, loaded bool) {
+	return s.shard(k).Swap(k, v)
+}
+
+// LoadAndDelete same as Map.LoadAndDelete
+func (s *Sharded[K, V]) LoadAndDelete(k K) (V, bool) {
+	return s.shard(k).LoadAndDelete(k)
+}
+
+// CasSetFunc same as Map.CasSetFunc
+func (s *Sharded[K, V]) CasSetFunc(k K, v V, lastv V, eq func(a, b V) bool) bool {
+	return s.shard(k).CasSetFunc(k, v, lastv, eq)
+}
+
+// Get get value of the given k, if k not exists, return the zero value
+func (s *Sharded[K, V]) Get(k K) V {
+	return s.shard(k).Get(k)
+}
+
+// GetCheck get value of the given k, if k exists.
+// if k not exists, ok is false
+func (s *Sharded[K, V]) GetCheck(k K) (V, bool) {
+	return s.shard(k).GetCheck(k)
+}
+
+// GetAll get a copy of all the shards, the shards are copied one by one,
+// so it is not a snapshot of a moment if there are concurrent writers
+func (s *Sharded[K, V]) GetAll() map[K]V {
+	cmap := make(map[K]V)
+	for _, m := range s.shards {
+		m.l.RLock()
+		for k, v := range m.m {
+			cmap[k] = v
+		}
+		m.l.RUnlock()
+	}
+	return cmap
+}
+
+// Exist check given k is exists
+func (s *Sharded[K, V]) Exist(k K) bool {
+	return s.shard(k).Exist(k)
+}
+
+// Del del the given key
+func (s *Sharded[K, V]) Del(k K) {
+	s.shard(k).Del(k)
+}
+
+// DelAll delete all keys, shard by shard
+func (s *Sharded[K, V]) DelAll() {
+	for _, m := range s.shards {
+		m.DelAll()
+	}
+}
+
+// Len get the map keys count, the sum of the shards
+func (s *Sharded[K, V]) Len() int {
+	n := 0
+	for _, m := range s.shards {
+		n += m.Len()
+	}
+	return n
+}
@@ -0,0 +1,149 @@
+package safemap
+
+import (
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSharded(t *testing.T) {
+	m := NewSharded[string, int](4, nil)
+
+	if !m.Add("a", 1) || m.Add("a", 2) {
+		t.Fatal("Add failed")
+	}
+	m.Set("b", 2)
+	if v := m.Get("a"); v != 1 {
+		t.Fatalf(`m.Get("a") = %d`, v)
+	}
+	if v, ok := m.GetCheck("nope"); ok || v != 0 {
+		t.Fatalf(`m.GetCheck("nope") = %d, %v`, v, ok)
+	}
+	if !m.Exist("b") || m.Exist("nope") {
+		t.Fatal("Exist failed")
+	}
+	if !m.CasSet("a", 3, 1) || m.CasSet("a", 4, 1) {
+		t.Fatal("CasSet failed")
+	}
+	if !m.CasMultiSet(map[string]int{"a": 10, "b": 20, "c": 30}, map[string]int{"a": 3, "b": 2}) {
+		t.Fatal("CasMultiSet failed")
+	}
+	if m.CasMultiSet(map[string]int{"a": 0}, map[string]int{"a": 3}) {
+		t.Fatal("CasMultiSet with stale values should fail")
+	}
+	if all := m.GetAll(); !reflect.DeepEqual(all, map[string]int{"a": 10, "b": 20, "c": 30}) {
+		t.Fatalf("m.GetAll() = %v", all)
+	}
+	m.Del("a")
+	if m.Len() != 2 {
+		t.Fatalf("m.Len() = %d", m.Len())
+	}
+	m.DelAll()
+	if m.Len() != 0 {
+		t.Fatalf("m.Len() = %d after DelAll", m.Len())
+	}
+}
+
+func TestShardedHash(t *testing.T) {
+	m := NewSharded[int, int](8, func(k int) uint64 { return uint64(k) })
+	for i := 0; i < 64; i++ {
+		m.Set(i, i)
+	}
+	for i, s := range m.shards {
+		if s.Len() != 8 {
+			t.Fatalf("shard %d has %d keys, want 8", i, s.Len())
+		}
+		for k := range s.GetAll() {
+			if k%8 != i {
+				t.Fatalf("key %d in shard %d", k, i)
+			}
+		}
+	}
+	if len(NewSharded[int, int](0, nil).shards) != DefaultShards {
+		t.Fatal("the default shard count not used")
+	}
+}
+
+// the concurrent CasMultiSet of overlapping keys in any order must not deadlock
+func TestShardedCasMultiSetConcurrent(t *testing.T) {
+	m := NewSharded[int, int](16, nil)
+	for k := 0; k < 32; k++ {
+		m.Set(k, 0)
+	}
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(seed))
+			for i := 0; i < 1000; i++ {
+				a, b := r.Intn(32), r.Intn(32)
+				for {
+					old := m.GetAll()
+					update := map[int]int{a: old[a] + 1}
+					if b != a {
+						update[b] = old[b] + 1
+					}
+					if m.CasMultiSet(update, map[int]int{a: old[a], b: old[b]}) {
+						break
+					}
+				}
+			}
+		}(int64(g))
+	}
+	wg.Wait()
+
+	sum := 0
+	for _, v := range m.GetAll() {
+		sum += v
+	}
+	if sum < 8*1000 || sum > 8*2000 {
+		t.Fatalf("sum of the counters = %d", sum)
+	}
+}
+
+// the parallel benchmarks of a 90% read, 10% write workload
+
+const benchKeys = 1024
+
+func benchParallel(b *testing.B, get func(k int), set func(k int)) {
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			k := r.Intn(benchKeys)
+			if k%10 == 0 {
+				set(k)
+			} else {
+				get(k)
+			}
+		}
+	})
+}
+
+func BenchmarkSafeMapParallel(b *testing.B) {
+	m := New()
+	for k := 0; k < benchKeys; k++ {
+		m.Set(k, k)
+	}
+	b.ResetTimer()
+	benchParallel(b, func(k int) { m.Get(k) }, func(k int) { m.Set(k, k) })
+}
+
+func BenchmarkShardedParallel(b *testing.B) {
+	m := NewSharded[int, int](0, nil)
+	for k := 0; k < benchKeys; k++ {
+		m.Set(k, k)
+	}
+	b.ResetTimer()
+	benchParallel(b, func(k int) { m.Get(k) }, func(k int) { m.Set(k, k) })
+}
+
+func BenchmarkSyncMapParallel(b *testing.B) {
+	m := &sync.Map{}
+	for k := 0; k < benchKeys; k++ {
+		m.Store(k, k)
+	}
+	b.ResetTimer()
+	benchParallel(b, func(k int) { m.Load(k) }, func(k int) { m.Store(k, k) })
+}
@@ -0,0 +1,368 @@
+package safemap
+
+import (
+	"sync"
+	"time"
+)
+
+// EvictReason is why an entry left a TTLMap
+type EvictReason int
+
+// evict reasons
+const (
+	Expired  EvictReason = iota // the ttl passed
+	Deleted                     // removed by Del or DelAll
+	Replaced                    // overwritten by a set of the same key
+)
+
+func (r EvictReason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Deleted:
+		return "deleted"
+	case Replaced:
+		return "replaced"
+	}
+	return "unknown"
+}
+
+// Clock supply the current time to a TTLMap, replaced by a fake one in tests
+type Clock interface {
+	Now() time.Time
+}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+type ttlOptions struct {
+	ttl     time.Duration
+	clock   Clock
+	janitor time.Duration
+}
+
+// TTLOption configure a TTLMap
+type TTLOption func(*ttlOptions)
+
+// WithDefaultTTL set the ttl of the entries set without one, the entries never expire if ttl <= 0
+func WithDefaultTTL(ttl time.Duration) TTLOption {
+	return func(o *ttlOptions) {
+		o.ttl = ttl
+	}
+}
+
+// WithClock set the clock to check the expiry, the system clock by default
+func WithClock(c Clock) TTLOption {
+	return func(o *ttlOptions) {
+		o.clock = c
+	}
+}
+
+// WithJanitor start a goroutine to remove the expired entries every interval,
+// stop it by TTLMap.Stop. without it the expired entries are only removed when read
+func WithJanitor(interval time.Duration) TTLOption {
+	return func(o *ttlOptions) {
+		o.janitor = interval
+	}
+}
+
+type ttlEntry[V any] struct {
+	v      V
+	expire time.Time // zero if never expire
+}
+
+type eviction[K comparable, V any] struct {
+	k      K
+	v      V
+	reason EvictReason
+}
+
+// TTLMap is a concurrency map whose entries expire after their ttl,
+// it has the Add, Set, CasSet, CasMultiSet, Get, GetCheck, GetAll, Exist, Del, DelAll
+// and Len methods of Map, the expired entries are treated as not exists
+type TTLMap[K comparable, V any] struct {
+	l       sync.RWMutex
+	m       map[K]ttlEntry[V]
+	ttl     time.Duration
+	clock   Clock
+	onEvict func(k K, v V, reason EvictReason)
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// NewTTL return an inited ttl map
+func NewTTL[K comparable, V any](opts ...TTLOption) *TTLMap[K, V] {
+	o := ttlOptions{clock: systemClock{}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	m := &TTLMap[K, V]{
+		m:     make(map[K]ttlEntry[V]),
+		ttl:   o.ttl,
+		clock: o.clock,
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+	if o.janitor > 0 {
+		go m.janitor(o.janitor)
+	} else {
+		close(m.done)
+	}
+	return m
+}
+
+func (m *TTLMap[K, V]) janitor(interval time.Duration) {
+	defer close(m.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.DeleteExpired()
+		case <-m.stop:
+			return
+		}
+	}
+}
+
+// Stop stop the janitor and wait it exit, the map is still usable
+func (m *TTLMap[K, V]) Stop() {
+	m.stopOnce.Do(func() {
+		close(m.stop)
+	})
+	<-m.done
+}
+
+// OnEvict set the callback of the entries leaving the map, it is called
+// without holding the lock of the map, so it can use the map
+func (m *TTLMap[K, V]) OnEvict(f func(k K, v V, reason EvictReason)) {
+	m.l.Lock()
+	defer m.l.Unlock()
+	m.onEvict = f
+}
+
+func (m *TTLMap[K, V]) entry(v V, ttl time.Duration) ttlEntry[V] {
+	e := ttlEntry[V]{v: v}
+	if ttl > 0 {
+		e.expire = m.clock.Now().Add(ttl)
+	}
+	return e
+}
+
+func (e ttlEntry[V]) expired(now time.Time) bool {
+	return !e.expire.IsZero() && !now.Before(e.expire)
+}
+
+// live return the unexpired entry of k, must hold the lock
+func (m *TTLMap[K, V]) live(k K, now time.Time) (ttlEntry[V], bool) {
+	e, ok := m.m[k]
+	if !ok || e.expired(now) {
+		return e, false
+	}
+	return e, true
+}
+
+// put set the entry of k and record the eviction of the old one, must hold the lock
+func (m *TTLMap[K, V]) put(k K, e ttlEntry[V], now time.Time, evicted []eviction[K, V]) []eviction[K, V] {
+	if old, ok := m.m[k]; ok {
+		reason := Replaced
+		if old.expired(now) {
+			reason = Expired
+		}
+		evicted = append(evicted, eviction[K, V]{k, old.v, reason})
+	}
+	m.m[k] = e
+	return evicted
+}
+
+// unlock release the lock, then call the callback of the evicted entries
+func (m *TTLMap[K, V]) unlock(evicted []eviction[K, V]) {
+	f := m.onEvict
+	m.l.Unlock()
+	if f == nil {
+		return
+	}
+	for _, ev := range evicted {
+		f(ev.k, ev.v, ev.reason)
+	}
+}
+
+// Add if k already in the map, return false
+func (m *TTLMap[K, V]) Add(k K, v V) bool {
+	return m.AddWithTTL(k, v, m.ttl)
+}
+
+// AddWithTTL same as Add, but the entry expire after ttl
+func (m *TTLMap[K, V]) AddWithTTL(k K, v V, ttl time.Duration) bool {
+	m.l.Lock()
+	now := m.clock.Now()
+	var evicted []eviction[K, V]
+	_, ok := m.live(k, now)
+	if !ok {
+		evicted = m.put(k, m.entry(v, ttl), now, evicted)
+	}
+	m.unlock(evicted)
+	return !ok
+}
+
+// Set set key k with value v, expire after the default ttl
+func (m *TTLMap[K, V]) Set(k K, v V) {
+	m.SetWithTTL(k, v, m.ttl)
+}
+
+// SetWithTTL set key k with value v, expire after ttl, never expire if ttl <= 0
+func (m *TTLMap[K, V]) SetWithTTL(k K, v V, ttl time.Duration) {
+	m.l.Lock()
+	evicted := m.put(k, m.entry(v, ttl), m.clock.Now(), nil)
+	m.unlock(evicted)
+}
+
+// CasSet compare and set v, the ttl of the entry is reset to the default one
+func (m *TTLMap[K, V]) CasSet(k K, v V, lastv V) bool {
+	m.l.Lock()
+	now := m.clock.Now()
+	var evicted []eviction[K, V]
+	e, ok := m.live(k, now)
+	set := !ok || equal(e.v, lastv)
+	if set {
+		evicted = m.put(k, m.entry(v, m.ttl), now, evicted)
+	}
+	m.unlock(evicted)
+	return set
+}
+
+// CasMultiSet compare and update multiple, the ttl of the updated entries are reset to the default one
+func (m *TTLMap[K, V]) CasMultiSet(update, old map[K]V) bool {
+	m.l.Lock()
+	now := m.clock.Now()
+	var evicted []eviction[K, V]
+
+	// old value compare
+	for k, v := range old {
+		if e, ok := m.live(k, now); !ok || !equal(e.v, v) {
+			m.unlock(nil)
+			return false
+		}
+	}
+	// new value set
+	for k, v := range update {
+		evicted = m.put(k, m.entry(v, m.ttl), now, evicted)
+	}
+	m.unlock(evicted)
+	return true
+}
+
+// Get get value of the given k, if k not exists or expired, return the zero value
+func (m *TTLMap[K, V]) Get(k K) V {
+	v, _ := m.GetCheck(k)
+	return v
+}
+
+// GetCheck get value of the given k, if k exists.
+// if k not exists or expired, ok is false, and the expired entry is removed
+func (m *TTLMap[K, V]) GetCheck(k K) (V, bool) {
+	m.l.RLock()
+	now := m.clock.Now()
+	e, ok := m.m[k]
+	if !ok || !e.expired(now) {
+		m.l.RUnlock()
+		return e.v, ok
+	}
+	m.l.RUnlock()
+
+	// lazy expiry, the entry may be changed before the write lock
+	m.l.Lock()
+	var evicted []eviction[K, V]
+	if e, ok := m.m[k]; ok && e.expired(now) {
+		delete(m.m, k)
+		evicted = append(evicted, eviction[K, V]{k, e.v, Expired})
+	}
+	m.unlock(evicted)
+	var zero V
+	return zero, false
+}
+
+// GetAll get a copy of the unexpired entries
+func (m *TTLMap[K, V]) GetAll() map[K]V {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	now := m.clock.Now()
+	cmap := make(map[K]V)
+	for k, e := range m.m {
+		if !e.expired(now) {
+			cmap[k] = e.v
+		}
+	}
+	return cmap
+}
+
+// Exist check given k is exists and not expired
+func (m *TTLMap[K, V]) Exist(k K) bool {
+	_, ok := m.GetCheck(k)
+	return ok
+}
+
+// Del del the given key
+func (m *TTLMap[K, V]) Del(k K) {
+	m.l.Lock()
+	now := m.clock.Now()
+	var evicted []eviction[K, V]
+	if e, ok := m.m[k]; ok {
+		reason := Deleted
+		if e.expired(now) {
+			reason = Expired
+		}
+		delete(m.m, k)
+		evicted = append(evicted, eviction[K, V]{k, e.v, reason})
+	}
+	m.unlock(evicted)
+}
+
+// DelAll delete all keys
+func (m *TTLMap[K, V]) DelAll() {
+	m.l.Lock()
+	now := m.clock.Now()
+	evicted := make([]eviction[K, V], 0, len(m.m))
+	for k, e := range m.m {
+		reason := Deleted
+		if e.expired(now) {
+			reason = Expired
+		}
+		evicted = append(evicted, eviction[K, V]{k, e.v, reason})
+	}
+	m.m = make(map[K]ttlEntry[V])
+	m.unlock(evicted)
+}
+
+// DeleteExpired remove all the expired entries, the janitor call it every interval
+func (m *TTLMap[K, V]) DeleteExpired() {
+	m.l.Lock()
+	now := m.clock.Now()
+	var evicted []eviction[K, V]
+	for k, e := range m.m {
+		if e.expired(now) {
+			delete(m.m, k)
+			evicted = append(evicted, eviction[K, V]{k, e.v, Expired})
+		}
+	}
+	m.unlock(evicted)
+}
+
+// Len get the count of the unexpired keys
+func (m *TTLMap[K, V]) Len() int {
+	m.l.RLock()
+	defer m.l.RUnlock()
+	now := m.clock.Now()
+	n := 0
+	for _, e := range m.m {
+		if !e.expired(now) {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,169 @@
+package safemap
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a manually advanced clock
+type fakeClock struct {
+	l   sync.Mutex
+	now time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.l.Lock()
+	defer c.l.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.l.Lock()
+	c.now = c.now.Add(d)
+	c.l.Unlock()
+}
+
+// evictLog record the evictions as "k=v:reason"
+type evictLog struct {
+	l    sync.Mutex
+	logs []string
+}
+
+func (e *evictLog) add(k string, v int, reason EvictReason) {
+	e.l.Lock()
+	e.logs = append(e.logs, fmt.Sprintf("%s=%d:%s", k, v, reason))
+	e.l.Unlock()
+}
+
+// take return the sorted logs and reset them
+func (e *evictLog) take() []string {
+	e.l.Lock()
+	defer e.l.Unlock()
+	logs := e.logs
+	e.logs = nil
+	sort.Strings(logs)
+	return logs
+}
+
+func TestTTLMap(t *testing.T) {
+	clock := newFakeClock()
+	m := NewTTL[string, int](WithDefaultTTL(time.Minute), WithClock(clock))
+	log := &evictLog{}
+	m.OnEvict(log.add)
+
+	m.Set("a", 1)
+	m.SetWithTTL("b", 2, time.Second)
+	m.SetWithTTL("forever", 3, 0)
+	if m.Len() != 3 || m.Get("b") != 2 {
+		t.Fatal("set failed")
+	}
+
+	clock.Advance(time.Second)
+	if v, ok := m.GetCheck("b"); ok || v != 0 {
+		t.Fatalf(`m.GetCheck("b") = %d, %v after the ttl`, v, ok)
+	}
+	if logs := log.take(); !reflect.DeepEqual(logs, []string{"b=2:expired"}) {
+		t.Fatalf("evicted = %v", logs)
+	}
+	if m.Exist("b") || m.Len() != 2 {
+		t.Fatal("the expired entry not removed")
+	}
+
+	m.Set("a", 10)
+	m.Del("forever")
+	m.Del("nope")
+	if logs := log.take(); !reflect.DeepEqual(logs, []string{"a=1:replaced", "forever=3:deleted"}) {
+		t.Fatalf("evicted = %v", logs)
+	}
+
+	// the expired entries are treated as not exists
+	m.SetWithTTL("c", 4, time.Second)
+	clock.Advance(time.Second)
+	if !m.Add("c", 5) || m.Get("c") != 5 {
+		t.Fatal("Add of an expired key failed")
+	}
+	if m.Add("c", 6) {
+		t.Fatal("Add of a live key should fail")
+	}
+	if logs := log.take(); !reflect.DeepEqual(logs, []string{"c=4:expired"}) {
+		t.Fatalf("evicted = %v", logs)
+	}
+
+	if !m.CasSet("c", 7, 5) || m.CasSet("c", 8, 5) || m.Get("c") != 7 {
+		t.Fatal("CasSet failed")
+	}
+	if !m.CasMultiSet(map[string]int{"a": 11, "c": 9}, map[string]int{"a": 10, "c": 7}) {
+		t.Fatal("CasMultiSet failed")
+	}
+	if logs := log.take(); !reflect.DeepEqual(logs, []string{"a=10:replaced", "c=5:replaced", "c=7:replaced"}) {
+		t.Fatalf("evicted = %v", logs)
+	}
+
+	clock.Advance(time.Minute)
+	if all := m.GetAll(); len(all) != 0 {
+		t.Fatalf("m.GetAll() = %v after the default ttl", all)
+	}
+	m.SetWithTTL("d", 1, time.Hour)
+	m.DeleteExpired()
+	if logs := log.take(); !reflect.DeepEqual(logs, []string{"a=11:expired", "c=9:expired"}) {
+		t.Fatalf("evicted = %v", logs)
+	}
+	m.DelAll()
+	if logs := log.take(); !reflect.DeepEqual(logs, []string{"d=1:deleted"}) || m.Len() != 0 {
+		t.Fatalf("evicted = %v", logs)
+	}
+}
+
+func TestTTLMapJanitor(t *testing.T) {
+	clock := newFakeClock()
+	m := NewTTL[string, int](WithDefaultTTL(time.Second), WithClock(clock), WithJanitor(time.Millisecond))
+	defer m.Stop()
+	evicted := make(chan string, 1)
+	m.OnEvict(func(k string, v int, reason EvictReason) {
+		evicted <- k + ":" + reason.String()
+	})
+
+	m.Set("a", 1)
+	clock.Advance(time.Second)
+	select {
+	case s := <-evicted:
+		if s != "a:expired" {
+			t.Fatalf("evicted %s", s)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the janitor not remove the expired entry")
+	}
+
+	m.Stop()
+	m.Set("b", 2)
+	clock.Advance(time.Second)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case s := <-evicted:
+		t.Fatalf("evicted %s after Stop", s)
+	default:
+	}
+}
+
+// the callbacks are called without the lock, so they can use the map
+func TestTTLMapEvictReenter(t *testing.T) {
+	m := NewTTL[string, int]()
+	m.OnEvict(func(k string, v int, reason EvictReason) {
+		if reason == Deleted {
+			m.Set(k+"-deleted", v)
+		}
+	})
+	m.Set("a", 1)
+	m.Del("a")
+	if m.Get("a-deleted") != 1 {
+		t.Fatal("the callback not set the key")
+	}
+}
@@ -0,0 +1,186 @@
+// cmtjson validate, format and query the commented json config files:
+//
+//	cmtjson check app.json db.json          # print the positioned syntax errors, exit 1 if any
+//	cmtjson fmt -w app.json                 # re-indent in place, the comments are kept
+//	cmtjson fmt -strip app.json             # pretty-print without the comments
+//	cmtjson fmt -minify app.json            # minify, the comments are always stripped
+//	cmtjson get app.json db.pool.max        # print the value of a dotted path
+//	cmtjson json app.json | jq .            # convert to plain json
+//
+// get and json read the file by config.ParseFromFile, so the "@include" directives
+// and "${...}" references are resolved, and the yaml and toml files are accepted
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/iyidan/goutils/cmtjson"
+	"github.com/iyidan/goutils/config"
+	"github.com/iyidan/goutils/mise"
+)
+
+var commands = map[string]func(args []string) error{
+	"check": check,
+	"fmt":   format,
+	"get":   get,
+	"json":  toJSON,
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `usage: %s <command> [flags] file...
+
+commands:
+  check file...          validate the files and print the syntax errors
+  fmt [flags] file       pretty-print or minify a file
+  get [flags] file path  print the value of a dotted path, like "servers[0].host"
+  json [flags] file      convert a config file to plain json
+
+run "%s <command> -h" for the flags of a command
+`, os.Args[0], os.Args[0])
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// newFlagSet return the flags of a command, which print the args usage on error
+func newFlagSet(name string, argsUsage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s %s [flags] %s\n", os.Args[0], name, argsUsage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func check(args []string) error {
+	fs := newFlagSet("check", "file...")
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	failed := 0
+	for _, filename := range fs.Args() {
+		var v interface{}
+		err := cmtjson.ParseFromFile(filename, &v)
+		if err == nil {
+			continue
+		}
+		failed++
+		fmt.Fprintln(os.Stderr, err)
+		if serr, ok := err.(*cmtjson.SyntaxError); ok && serr.Excerpt != "" {
+			fmt.Fprintln(os.Stderr, serr.Excerpt)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d files invalid", failed, fs.NArg())
+	}
+	return nil
+}
+
+func format(args []string) error {
+	fs := newFlagSet("fmt", "file")
+	indent := fs.String("indent", "    ", "the indent string")
+	strip := fs.Bool("strip", false, "strip the comments")
+	minify := fs.Bool("minify", false, "minify, implies -strip")
+	write := fs.Bool("w", false, "write the result to the file instead of stdout")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	filename := fs.Arg(0)
+
+	doc, err := cmtjson.ParseASTFromFile(filename)
+	if err != nil {
+		return err
+	}
+	var out []byte
+	if *strip || *minify {
+		// the source is valid, so the stripped one is plain json
+		data := bytes.TrimSpace(cmtjson.RemoveJSONCommentBytes(doc.Bytes()))
+		buf := &bytes.Buffer{}
+		if *minify {
+			err = json.Compact(buf, data)
+		} else {
+			err = json.Indent(buf, data, "", *indent)
+		}
+		if err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		out = buf.Bytes()
+	} else {
+		out = doc.Format(*indent)
+	}
+
+	if !*write {
+		_, err = os.Stdout.Write(out)
+		return err
+	}
+	return mise.WriteFileAtomic(filename, out, 0644)
+}
+
+func get(args []string) error {
+	fs := newFlagSet("get", "file path")
+	indent := fs.String("indent", "    ", "the indent string of the objects and arrays")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	conf, err := config.ParseFromFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	v, err := conf.GetE(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	// print a string as is for the shell scripts
+	if s, ok := v.(string); ok {
+		fmt.Println(s)
+		return nil
+	}
+	return writeJSON(v, *indent)
+}
+
+func toJSON(args []string) error {
+	fs := newFlagSet("json", "file")
+	indent := fs.String("indent", "", "the indent string, compact if empty")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	conf, err := config.ParseFromFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return writeJSON(conf.AllSettings(), *indent)
+}
+
+// writeJSON print v as json to stdout, the html characters are not escaped
+func writeJSON(v interface{}, indent string) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", indent)
+	return enc.Encode(v)
+}
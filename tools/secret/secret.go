@@ -0,0 +1,108 @@
+// secret encrypt or decrypt the string values of a commented json config file in place,
+// the comments and layout of the file are kept:
+//
+//	secret -genkey > config.key
+//	secret -key-file config.key -f app.json db.password redis.auth
+//	secret -key-file config.key -f app.json -d db.password
+//
+// the encrypted values look like "ENC[AES256_GCM,...]", config.Config decrypt them
+// when read with the config.WithKeyProvider option
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/iyidan/goutils/cmtjson"
+	"github.com/iyidan/goutils/config"
+	"github.com/iyidan/goutils/mise"
+)
+
+var (
+	genkey   bool
+	decrypt  bool
+	keyFile  string
+	keyEnv   string
+	filename string
+)
+
+func init() {
+	flag.BoolVar(&genkey, "genkey", false, "print a new random key")
+	flag.BoolVar(&decrypt, "d", false, "decrypt the values instead of encrypt")
+	flag.StringVar(&keyFile, "key-file", "", "file of the base64 encoded key")
+	flag.StringVar(&keyEnv, "key-env", "CONFIG_SECRET_KEY", "environment variable of the base64 encoded key, if no -key-file")
+	flag.StringVar(&filename, "f", "", "the commented json config file")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] path...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+}
+
+func main() {
+	flag.Parse()
+	if genkey {
+		key, err := config.GenerateKey()
+		exitOnError(err)
+		fmt.Println(key)
+		return
+	}
+	if len(filename) <= 0 || flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	kp := config.EnvKey(keyEnv)
+	if keyFile != "" {
+		kp = config.FileKey(keyFile)
+	}
+	key, err := kp.Key()
+	exitOnError(err)
+
+	doc, err := cmtjson.ParseASTFromFile(filename)
+	exitOnError(err)
+
+	for _, path := range flag.Args() {
+		exitOnError(convert(doc, path, key))
+	}
+	exitOnError(mise.WriteFileAtomic(filename, doc.Bytes(), 0644))
+}
+
+// convert encrypt or decrypt the string value of path
+func convert(doc *cmtjson.Document, path string, key []byte) error {
+	n := doc.Find(path)
+	if n == nil {
+		return fmt.Errorf("%s not exists", path)
+	}
+	if n.Kind != cmtjson.StringNode {
+		return fmt.Errorf("%s is a %s, want a string", path, n.Kind)
+	}
+	v, err := n.Interface()
+	if err != nil {
+		return err
+	}
+	s := v.(string)
+
+	if decrypt {
+		if !config.IsEncrypted(s) {
+			return nil
+		}
+		s, err = config.Decrypt(key, s)
+	} else {
+		if config.IsEncrypted(s) {
+			return nil
+		}
+		s, err = config.Encrypt(key, s)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", path, err)
+	}
+	return n.SetValue(s)
+}
+
+func exitOnError(err error) {
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}